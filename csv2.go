@@ -1,15 +1,28 @@
 package csv2
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"math"
+	"math/big"
 	"math/bits"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,230 +32,1422 @@ const (
 
 	// Format name
 	TagFormat = "format"
+
+	// Header value for skipping the field
+	TagHeaderSkip = "-"
+
+	// Column index name
+	TagIndex = "index"
+
+	// Default value name
+	TagDefault = "default"
+
+	// Write header name
+	TagWriteHeader = "writeHeader"
+
+	// Allowed values name
+	TagEnum = "enum"
+
+	// Trimmed characters name
+	TagTrim = "trim"
+
+	// Value transforms name
+	TagTransform = "transform"
+
+	// Required value name
+	TagRequired = "required"
+
+	// Combined tag name for the header followed by the key=value options
+	TagCSV = "csv"
+)
+
+const (
+	// Format for Unix time in seconds
+	FormatUnix = "unix"
+
+	// Format for Unix time in milliseconds
+	FormatUnixMilli = "unixmilli"
+
+	// Format for booleans that are true if the cell is not empty
+	FormatNonEmpty = "nonempty"
+
+	// Format for values encoded as JSON
+	FormatJSON = "json"
+
+	// Format for byte slices encoded as standard base64
+	FormatBase64 = "base64"
+
+	// Format for percentages read as fractions, such as 12.5% for 0.125
+	FormatPercent = "percent"
+
+	// Format for percentages read as is, such as 12.5% for 12.5
+	FormatPercentLiteral = "percent-literal"
+
+	// Format for Excel serial dates in days since 1899-12-30, with the time
+	// of the day as the fraction
+	FormatExcel = "excel"
+)
+
+const (
+	// Transform for lowercasing the cells
+	TransformLower = "lower"
+
+	// Transform for uppercasing the cells
+	TransformUpper = "upper"
+)
+
+const (
+	timeFormat = "2006-01-02 15:04:05"
+
+	// Format prefix for the integer base
+	formatBase = "base="
+
+	// Separator of the header name and its occurrence
+	headerOccurrenceSeparator = "#"
+
+	// Extension of the gzip compressed files
+	gzipExtension = ".gz"
+
+	// Suffix of the header tag for prefixing the nested struct headers
+	headerPrefixSuffix = "."
+
+	// Separator of the candidate time layouts and the enum values
+	listSeparator = "|"
 )
 
-const (
-	timeFormat = "2006-01-02 15:04:05"
-)
+// Prefixes allowed for the integer bases.
+var basePrefixes = map[int]string{
+	2:  "0b",
+	8:  "0o",
+	16: "0x",
+}
+
+// Values accepted by strconv.ParseBool.
+var boolValues = []string{"1", "t", "T", "TRUE", "true", "True", "0", "f", "F", "FALSE", "false", "False"}
+
+// Unmarshaler is the interface implemented by types that can unmarshal a CSV cell of themselves.
+type Unmarshaler interface {
+	UnmarshalCSV(string) error
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var ipType = reflect.TypeOf(net.IP{})
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// Struct types parsed by their type. The named types based on them are
+// converted to and from these.
+var structTypes = []reflect.Type{
+	reflect.TypeOf(time.Time{}),
+	reflect.TypeOf(url.URL{}),
+	reflect.TypeOf(big.Int{}),
+	reflect.TypeOf(big.Float{}),
+}
+
+// isNullType checks if the given struct type is one of the nullable types of
+// the database/sql package, such as sql.NullString or sql.Null[T], which have
+// a value field followed by a Valid field.
+func isNullType(structType reflect.Type) bool {
+	return structType.PkgPath() == "database/sql" &&
+		strings.HasPrefix(structType.Name(), "Null") &&
+		structType.NumField() == 2 &&
+		structType.Field(1).Name == "Valid"
+}
+
+func setNullValue(value reflect.Value, stringValue string, format string, options *Options) error {
+	value.Set(reflect.Zero(value.Type()))
+
+	if stringValue == "" {
+		return nil
+	}
+
+	if err := setValue(value.Field(0), stringValue, format, options); err != nil {
+		return err
+	}
+
+	value.Field(1).SetBool(true)
+
+	return nil
+}
+
+// baseStructType returns the supported struct type that the given named
+// struct type can be converted to, or nil if there is none.
+func baseStructType(structType reflect.Type) reflect.Type {
+	for _, baseType := range structTypes {
+		if structType != baseType && structType.ConvertibleTo(baseType) {
+			return baseType
+		}
+	}
+
+	return nil
+}
+
+// Column describes how a struct field maps to a CSV column.
+type Column struct {
+	// Name is the name of the struct field.
+	Name string
+
+	// Header is the column header for the field.
+	Header string
+
+	// ColumnIndex is the 0-based index of the column in the record. It is -1
+	// if the header is not found, and the field is left with its zero value.
+	ColumnIndex int
+
+	// FieldIndex is the index sequence of the field in the struct.
+	FieldIndex []int
+
+	// Format is the format tag of the field.
+	Format string
+
+	// Default is the default tag of the field.
+	Default string
+
+	// WriteHeader is the column header used for writing instead of Header
+	// if it is not empty.
+	WriteHeader string
+
+	// Enum is the list of allowed values from the enum tag of the field.
+	Enum []string
+
+	// Trim is the set of characters from the trim tag of the field that are
+	// removed from both ends of the cells.
+	Trim string
+
+	// Transform is the list of transforms from the transform tag of the
+	// field that are applied to the cells in order.
+	Transform []string
+
+	// Required indicates that the cells of the field must not be empty.
+	Required bool
+}
+
+func setBoolValue(value reflect.Value, stringValue, format string, options *Options) error {
+	if format == FormatNonEmpty {
+		value.SetBool(stringValue != "")
+		return nil
+	}
+
+	for _, trueValue := range options.TrueValues {
+		if strings.EqualFold(trueValue, stringValue) {
+			value.SetBool(true)
+			return nil
+		}
+	}
+
+	for _, falseValue := range options.FalseValues {
+		if strings.EqualFold(falseValue, stringValue) {
+			value.SetBool(false)
+			return nil
+		}
+	}
+
+	actualValue, err := strconv.ParseBool(stringValue)
+	if err == nil {
+		value.SetBool(actualValue)
+	} else if len(options.TrueValues) > 0 || len(options.FalseValues) > 0 {
+		err = fmt.Errorf("invalid bool value %q, accepted values are %s, %s and %s",
+			stringValue,
+			strings.Join(options.TrueValues, ", "),
+			strings.Join(options.FalseValues, ", "),
+			strings.Join(boolValues, ", "))
+	}
+
+	return err
+}
+
+func parseBase(format string) (int, error) {
+	if !strings.HasPrefix(format, formatBase) {
+		return 10, nil
+	}
+
+	return strconv.Atoi(strings.TrimPrefix(format, formatBase))
+}
+
+func trimBasePrefix(stringValue string, base int) string {
+	prefix, ok := basePrefixes[base]
+	if !ok {
+		return stringValue
+	}
+
+	sign := ""
+	if strings.HasPrefix(stringValue, "-") || strings.HasPrefix(stringValue, "+") {
+		sign, stringValue = stringValue[:1], stringValue[1:]
+	}
+
+	if len(stringValue) >= len(prefix) && strings.EqualFold(stringValue[:len(prefix)], prefix) {
+		stringValue = stringValue[len(prefix):]
+	}
+
+	return sign + stringValue
+}
+
+func setIntValue(value reflect.Value, stringValue string, bitSize int, format string, options *Options) error {
+	base, err := parseBase(format)
+	if err != nil {
+		return fmt.Errorf("invalid base format %q", format)
+	}
+
+	stringValue = options.removeThousandsSeparator(stringValue)
+
+	actualValue, err := strconv.ParseInt(trimBasePrefix(stringValue, base), base, bitSize)
+	if err == nil {
+		value.SetInt(actualValue)
+	}
+
+	return err
+}
+
+func setUintValue(value reflect.Value, stringValue string, bitSize int, format string, options *Options) error {
+	base, err := parseBase(format)
+	if err != nil {
+		return fmt.Errorf("invalid base format %q", format)
+	}
+
+	stringValue = options.removeThousandsSeparator(stringValue)
+
+	actualValue, err := strconv.ParseUint(trimBasePrefix(stringValue, base), base, bitSize)
+	if err == nil {
+		value.SetUint(actualValue)
+	}
+
+	return err
+}
+
+func setFloatValue(value reflect.Value, stringValue string, bitSize int, format string, options *Options) error {
+	if format == FormatPercent || format == FormatPercentLiteral {
+		stringValue = strings.TrimSpace(strings.TrimSuffix(stringValue, "%"))
+	}
+
+	stringValue = options.normalizeFloat(stringValue)
+
+	if format == FormatPercent {
+		stringValue += "e-2"
+	}
+
+	actualValue, err := strconv.ParseFloat(stringValue, bitSize)
+	if err == nil {
+		value.SetFloat(actualValue)
+	}
+
+	return err
+}
+
+func setComplexValue(value reflect.Value, stringValue string, bitSize int) error {
+	actualValue, err := strconv.ParseComplex(stringValue, bitSize)
+	if err == nil {
+		value.SetComplex(actualValue)
+	}
+
+	return err
+}
+
+func parseUnixTime(stringValue string, format string) (time.Time, error) {
+	unixValue, err := strconv.ParseInt(stringValue, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if format == FormatUnixMilli {
+		return time.UnixMilli(unixValue).UTC(), nil
+	}
+
+	return time.Unix(unixValue, 0).UTC(), nil
+}
+
+// Epoch of the Excel serial dates.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+func parseExcelTime(stringValue string, location *time.Location) (time.Time, error) {
+	serialValue, err := strconv.ParseFloat(stringValue, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	days := math.Floor(serialValue)
+	dayTime := time.Duration((serialValue - days) * float64(24*time.Hour)).Round(time.Millisecond)
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	// The serial is the wall clock, so the time of the day is not added as a
+	// duration that would shift on the daylight saving time days.
+	return time.Date(1899, time.December, 30+int(days),
+		int(dayTime/time.Hour), int(dayTime%time.Hour/time.Minute), int(dayTime%time.Minute/time.Second),
+		int(dayTime%time.Second), location), nil
+}
+
+// parseTime parses the value with the layout. The times without a zone are
+// in the given location, or in UTC if it is nil.
+func parseTime(stringValue string, layout string, location *time.Location) (time.Time, error) {
+	switch layout {
+	case FormatUnix, FormatUnixMilli:
+		timeValue, err := parseUnixTime(stringValue, layout)
+		if err == nil && location != nil {
+			timeValue = timeValue.In(location)
+		}
+
+		return timeValue, err
+
+	case FormatExcel:
+		return parseExcelTime(stringValue, location)
+
+	default:
+		if location != nil {
+			return time.ParseInLocation(layout, stringValue, location)
+		}
+
+		return time.Parse(layout, stringValue)
+	}
+}
+
+func setTimeValue(value reflect.Value, stringValue string, format string, options *Options) error {
+	layouts := strings.Split(options.timeLayout(format), listSeparator)
+
+	for _, layout := range layouts {
+		actualValue, err := parseTime(stringValue, layout, options.Location)
+		if err == nil {
+			value.Set(reflect.ValueOf(actualValue))
+			return nil
+		}
+
+		if len(layouts) == 1 {
+			return err
+		}
+	}
+
+	return fmt.Errorf("cannot parse %q as any of the layouts %s", stringValue, strings.Join(layouts, ", "))
+}
+
+func setDurationValue(value reflect.Value, stringValue string) error {
+	actualValue, err := time.ParseDuration(stringValue)
+	if err == nil {
+		value.SetInt(int64(actualValue))
+	}
+
+	return err
+}
+
+func setIPValue(value reflect.Value, stringValue string) error {
+	actualValue := net.ParseIP(stringValue)
+	if actualValue == nil {
+		return fmt.Errorf("invalid IP address %q", stringValue)
+	}
+
+	value.Set(reflect.ValueOf(actualValue))
+
+	return nil
+}
+
+func setURLValue(value reflect.Value, stringValue string) error {
+	actualValue, err := url.Parse(stringValue)
+	if err == nil {
+		value.Set(reflect.ValueOf(*actualValue))
+	}
+
+	return err
+}
+
+func setBigIntValue(value reflect.Value, stringValue string) error {
+	if _, ok := value.Addr().Interface().(*big.Int).SetString(stringValue, 10); !ok {
+		return fmt.Errorf("invalid big integer value %q", stringValue)
+	}
+
+	return nil
+}
+
+func setBigFloatValue(value reflect.Value, stringValue string, options *Options) error {
+	if _, ok := value.Addr().Interface().(*big.Float).SetString(options.normalizeFloat(stringValue)); !ok {
+		return fmt.Errorf("invalid big float value %q", stringValue)
+	}
+
+	return nil
+}
+
+func setRawMessageValue(value reflect.Value, stringValue string) error {
+	if stringValue == "" {
+		value.Set(reflect.Zero(value.Type()))
+		return nil
+	}
+
+	if !json.Valid([]byte(stringValue)) {
+		return fmt.Errorf("invalid JSON value %q", stringValue)
+	}
+
+	value.Set(reflect.ValueOf(json.RawMessage(stringValue)))
+
+	return nil
+}
+
+func setBytesValue(value reflect.Value, stringValue string, format string) error {
+	if stringValue == "" {
+		value.Set(reflect.Zero(value.Type()))
+		return nil
+	}
+
+	actualValue := []byte(stringValue)
+
+	if format == FormatBase64 {
+		var err error
+
+		actualValue, err = base64.StdEncoding.DecodeString(stringValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	value.SetBytes(actualValue)
+
+	return nil
+}
+
+func setJSONValue(value reflect.Value, stringValue string) error {
+	if stringValue == "" {
+		value.Set(reflect.Zero(value.Type()))
+		return nil
+	}
+
+	return json.Unmarshal([]byte(stringValue), value.Addr().Interface())
+}
+
+func setPtrValue(value reflect.Value, stringValue string, format string, options *Options) error {
+	if stringValue == "" {
+		value.Set(reflect.Zero(value.Type()))
+		return nil
+	}
+
+	actualValue := reflect.New(value.Type().Elem())
+
+	err := setValue(actualValue.Elem(), stringValue, format, options)
+	if err == nil {
+		value.Set(actualValue)
+	}
+
+	return err
+}
+
+func setValue(value reflect.Value, stringValue string, format string, options *Options) error {
+	if format == FormatJSON {
+		return setJSONValue(value, stringValue)
+	}
+
+	if value.CanAddr() && value.Addr().Type().Implements(unmarshalerType) {
+		return value.Addr().Interface().(Unmarshaler).UnmarshalCSV(stringValue)
+	}
+
+	if value.Type() == durationType {
+		return setDurationValue(value, stringValue)
+	}
+
+	if value.Type() == ipType {
+		return setIPValue(value, stringValue)
+	}
+
+	if value.Type() == rawMessageType {
+		return setRawMessageValue(value, stringValue)
+	}
+
+	kind := value.Kind()
+
+	switch kind {
+	case reflect.String:
+		value.SetString(stringValue)
+		return nil
+
+	case reflect.Bool:
+		return setBoolValue(value, stringValue, format, options)
+
+	case reflect.Int:
+		return setIntValue(value, stringValue, bits.UintSize, format, options)
+
+	case reflect.Int8:
+		return setIntValue(value, stringValue, 8, format, options)
+
+	case reflect.Int16:
+		return setIntValue(value, stringValue, 16, format, options)
+
+	case reflect.Int32:
+		return setIntValue(value, stringValue, 32, format, options)
+
+	case reflect.Int64:
+		return setIntValue(value, stringValue, 64, format, options)
+
+	case reflect.Uint:
+		return setUintValue(value, stringValue, bits.UintSize, format, options)
+
+	case reflect.Uint8:
+		return setUintValue(value, stringValue, 8, format, options)
+
+	case reflect.Uint16:
+		return setUintValue(value, stringValue, 16, format, options)
+
+	case reflect.Uint32:
+		return setUintValue(value, stringValue, 32, format, options)
+
+	case reflect.Uint64:
+		return setUintValue(value, stringValue, 64, format, options)
+
+	case reflect.Float32:
+		return setFloatValue(value, stringValue, 32, format, options)
+
+	case reflect.Float64:
+		return setFloatValue(value, stringValue, 64, format, options)
+
+	case reflect.Complex64:
+		return setComplexValue(value, stringValue, 64)
+
+	case reflect.Complex128:
+		return setComplexValue(value, stringValue, 128)
+
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported slice type %s", value.Type())
+		}
+
+		return setBytesValue(value, stringValue, format)
+
+	case reflect.Ptr:
+		return setPtrValue(value, stringValue, format, options)
+
+	case reflect.Struct:
+		typeString := value.Type().String()
+
+		switch typeString {
+		case "time.Time":
+			return setTimeValue(value, stringValue, format, options)
+
+		case "url.URL":
+			return setURLValue(value, stringValue)
+
+		case "big.Int":
+			return setBigIntValue(value, stringValue)
+
+		case "big.Float":
+			return setBigFloatValue(value, stringValue, options)
+
+		default:
+			if isNullType(value.Type()) {
+				return setNullValue(value, stringValue, format, options)
+			}
+
+			baseType := baseStructType(value.Type())
+			if baseType == nil {
+				return fmt.Errorf("unsupported struct type %s", typeString)
+			}
+
+			baseValue := reflect.New(baseType).Elem()
+			if err := setValue(baseValue, stringValue, format, options); err != nil {
+				return err
+			}
+
+			value.Set(baseValue.Convert(value.Type()))
+			return nil
+		}
+
+	default:
+		return fmt.Errorf("unsupported value kind %s", kind)
+	}
+}
+
+// isSupportedType reports whether the values of the type can be read, if the
+// codec type is unmarshalerType, or written, if it is marshalerType.
+func isSupportedType(valueType reflect.Type, codecType reflect.Type) bool {
+	if reflect.PointerTo(valueType).Implements(codecType) {
+		return true
+	}
+
+	if valueType == durationType || valueType == ipType || valueType == rawMessageType {
+		return true
+	}
+
+	switch valueType.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+
+	case reflect.Slice:
+		return valueType.Elem().Kind() == reflect.Uint8
+
+	case reflect.Ptr:
+		return isSupportedType(valueType.Elem(), codecType)
+
+	case reflect.Struct:
+		return isBuiltinStructType(valueType)
+
+	default:
+		return false
+	}
+}
+
+// isBuiltinStructType reports whether the struct type is read and written as
+// a single cell without a marshaler.
+func isBuiltinStructType(structType reflect.Type) bool {
+	return structType.String() == "time.Time" ||
+		structType.String() == "url.URL" ||
+		structType.String() == "big.Int" ||
+		structType.String() == "big.Float" ||
+		baseStructType(structType) != nil ||
+		isNullType(structType)
+}
+
+// isLeafStructType reports whether the struct type is a single cell instead
+// of being flattened into columns.
+func isLeafStructType(structType reflect.Type) bool {
+	return isBuiltinStructType(structType) ||
+		reflect.PointerTo(structType).Implements(unmarshalerType) ||
+		reflect.PointerTo(structType).Implements(marshalerType)
+}
+
+// Cache of the columns for each struct type before header mapping.
+var columnsCache sync.Map
+
+func getColumns(structType reflect.Type) ([]Column, error) {
+	cached, ok := columnsCache.Load(structType)
+	if !ok {
+		columns, err := appendStructFieldsAsColumns(nil, structType, nil, "")
+		if err != nil {
+			return nil, err
+		}
+
+		cached, _ = columnsCache.LoadOrStore(structType, columns)
+	}
+
+	// Copy so that the header mapping doesn't modify the cached columns.
+	cachedColumns := cached.([]Column)
+	columns := make([]Column, len(cachedColumns))
+	copy(columns, cachedColumns)
+
+	return columns, nil
+}
+
+// checkColumnIndices checks that no two columns have the same column index,
+// either from their index tags or from their positions. It is used where the
+// indices are not mapped from a header, when reading without one and when
+// writing.
+func checkColumnIndices(columns []Column) error {
+	fields := make(map[int]string, len(columns))
+
+	for _, column := range columns {
+		if column.ColumnIndex < 0 {
+			continue
+		}
+
+		if name, ok := fields[column.ColumnIndex]; ok {
+			return fmt.Errorf("duplicate index %d for fields %s and %s", column.ColumnIndex, name, column.Name)
+		}
+
+		fields[column.ColumnIndex] = column.Name
+	}
+
+	return nil
+}
+
+// recordLength returns the number of fields of the records for the columns.
+func recordLength(columns []Column) int {
+	n := 0
+
+	for _, column := range columns {
+		n = max(n, column.ColumnIndex+1)
+	}
+
+	return n
+}
+
+// getStructFieldsAsColumns returns the columns of the struct type, checking
+// that the fields can be read or written as given by the codec type.
+func getStructFieldsAsColumns(structType reflect.Type, codecType reflect.Type) ([]Column, error) {
+	columns, err := getColumns(structType)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range columns {
+		field := structType.FieldByIndex(column.FieldIndex)
+		if column.Format != FormatJSON && !isSupportedType(field.Type, codecType) {
+			return nil, fmt.Errorf("unsupported type %s for field %s", field.Type, field.Name)
+		}
+	}
+
+	return columns, nil
+}
+
+// lookupTag looks up the given tag of the field. The options of the combined
+// csv tag take precedence over the individual tags.
+func lookupTag(field reflect.StructField, name string) (string, bool) {
+	if csvTag, ok := field.Tag.Lookup(TagCSV); ok {
+		header, options, _ := strings.Cut(csvTag, ",")
+
+		if name == TagHeader {
+			if header != "" {
+				return header, true
+			}
+		} else if options != "" {
+			for _, option := range strings.Split(options, ",") {
+				if key, value, found := strings.Cut(option, "="); found && key == name {
+					return value, true
+				}
+			}
+		}
+	}
+
+	return field.Tag.Lookup(name)
+}
+
+// appendStructFieldsAsColumns appends the fields of the struct type as
+// columns. The fields of the embedded structs without a header tag, and of
+// the nested structs with a header prefix tag ending with a dot, such as
+// "Bid.", are flattened into columns.
+func appendStructFieldsAsColumns(columns []Column, structType reflect.Type, parentIndex []int, headerPrefix string) ([]Column, error) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		fieldIndex := make([]int, len(parentIndex)+1)
+		copy(fieldIndex, parentIndex)
+		fieldIndex[len(parentIndex)] = i
+
+		header, ok := lookupTag(field, TagHeader)
+		if !ok {
+			header = field.Name
+		} else if header == TagHeaderSkip {
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct && !isLeafStructType(field.Type) {
+			isEmbedded := field.Anonymous && !ok
+			isNested := ok && strings.HasSuffix(header, headerPrefixSuffix)
+
+			if isEmbedded || isNested {
+				nestedPrefix := headerPrefix
+				if isNested {
+					nestedPrefix += header
+				}
+
+				var err error
+
+				columns, err = appendStructFieldsAsColumns(columns, field.Type, fieldIndex, nestedPrefix)
+				if err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+		}
+
+		// The unexported fields are skipped like in encoding/json, except for
+		// the embedded structs flattened above.
+		if !field.IsExported() {
+			continue
+		}
+
+		header = headerPrefix + header
+
+		format, _ := lookupTag(field, TagFormat)
+		defaultValue, _ := lookupTag(field, TagDefault)
+		writeHeader, _ := lookupTag(field, TagWriteHeader)
+		trim, _ := lookupTag(field, TagTrim)
+
+		columnIndex := len(columns)
+
+		if index, ok := lookupTag(field, TagIndex); ok {
+			var err error
+
+			columnIndex, err = strconv.Atoi(index)
+			if err != nil || columnIndex < 0 {
+				return nil, fmt.Errorf("invalid index %q for field %s", index, field.Name)
+			}
+		}
+
+		var enum []string
+		if enumValues, ok := lookupTag(field, TagEnum); ok {
+			enum = strings.Split(enumValues, listSeparator)
+		}
+
+		var transforms []string
+		if transformValues, ok := lookupTag(field, TagTransform); ok {
+			transforms = strings.Split(transformValues, listSeparator)
+
+			for _, transform := range transforms {
+				if transform != TransformLower && transform != TransformUpper {
+					return nil, fmt.Errorf("invalid transform %q for field %s", transform, field.Name)
+				}
+			}
+		}
+
+		required := false
+		if requiredValue, ok := lookupTag(field, TagRequired); ok {
+			var err error
+
+			required, err = strconv.ParseBool(requiredValue)
+			if err != nil {
+				return nil, fmt.Errorf("invalid required %q for field %s", requiredValue, field.Name)
+			}
+		}
+
+		columns = append(columns, Column{
+			Name:        field.Name,
+			Header:      header,
+			ColumnIndex: columnIndex,
+			FieldIndex:  fieldIndex,
+			Format:      format,
+			Default:     defaultValue,
+			WriteHeader: writeHeader,
+			Enum:        enum,
+			Trim:        trim,
+			Transform:   transforms,
+			Required:    required,
+		})
+	}
+
+	return columns, nil
+}
+
+// getTableFieldsAsColumns returns the columns of the table type, checking
+// that the fields are slices that can be read or written as given by the codec
+// type.
+func getTableFieldsAsColumns(tableType reflect.Type, codecType reflect.Type) ([]Column, error) {
+	columns, err := getColumns(tableType)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range columns {
+		field := tableType.FieldByIndex(column.FieldIndex)
+		if field.Type.Kind() != reflect.Slice {
+			return nil, ErrFieldsNotSlices
+		}
+
+		if column.Format != FormatJSON && !isSupportedType(field.Type.Elem(), codecType) {
+			return nil, fmt.Errorf("unsupported type %s for field %s", field.Type.Elem(), field.Name)
+		}
+	}
+
+	return columns, nil
+}
+
+// discardRecords reads and discards the given number of records, regardless
+// of their number of fields.
+func discardRecords(csvReader *csv.Reader, n int) error {
+	fieldsPerRecord := csvReader.FieldsPerRecord
+	csvReader.FieldsPerRecord = -1
+
+	defer func() {
+		csvReader.FieldsPerRecord = fieldsPerRecord
+	}()
+
+	for i := 0; i < n; i++ {
+		if _, err := csvReader.Read(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readHeader(csvReader *csv.Reader, options *Options) ([]string, error) {
+	if err := discardRecords(csvReader, options.HeaderRow); err != nil {
+		return nil, err
+	}
+
+	headers, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if options.TrimSpace {
+		for i := range headers {
+			headers[i] = strings.TrimSpace(headers[i])
+		}
+	}
+
+	return headers, nil
+}
+
+// parseHeaderOccurrence splits a header like "Price#2" into the header name
+// and the 1-based occurrence of that name in the file header.
+func parseHeaderOccurrence(header string) (string, int, bool) {
+	i := strings.LastIndex(header, headerOccurrenceSeparator)
+	if i == -1 {
+		return header, 1, false
+	}
+
+	occurrence, err := strconv.Atoi(header[i+len(headerOccurrenceSeparator):])
+	if err != nil || occurrence < 1 {
+		return header, 1, false
+	}
+
+	return header[:i], occurrence, true
+}
+
+// findHeader returns the index of the given 1-based occurrence of the name in
+// the headers, or -1 if it is not found, and the number of matches.
+func findHeader(headers []string, name string, occurrence int, options *Options) (int, int) {
+	index := -1
+	matches := 0
+
+	for j, header := range headers {
+		if options.matchHeader(name, header) {
+			matches++
+
+			if matches == occurrence {
+				index = j
+			}
+		}
+	}
+
+	return index, matches
+}
+
+func mapHeader(headers []string, columns []Column, options *Options) error {
+	var missing []string
+	var duplicates []string
+
+	for i := range columns {
+		columnHeader := columns[i].Header
+		if options.TrimSpace {
+			columnHeader = strings.TrimSpace(columnHeader)
+		}
+
+		// The whole header is matched first, so that the headers like
+		// "Phone #1" are not taken as occurrences.
+		index, matches := findHeader(headers, columnHeader, 1, options)
+		explicit := false
+
+		if index == -1 {
+			var name string
+			var occurrence int
+
+			name, occurrence, explicit = parseHeaderOccurrence(columnHeader)
+			if explicit {
+				index, matches = findHeader(headers, name, occurrence, options)
+			}
+		}
+
+		columns[i].ColumnIndex = index
+
+		if index == -1 {
+			missing = append(missing, columns[i].Header)
+		} else if !explicit && matches > 1 {
+			duplicates = append(duplicates, columns[i].Header)
+		}
+	}
+
+	if options.RequireAllColumns && len(missing) > 0 {
+		return fmt.Errorf("columns not found in header: %s", strings.Join(missing, ", "))
+	}
+
+	if options.RequireAllColumns && len(duplicates) > 0 {
+		return fmt.Errorf("duplicate columns in header: %s", strings.Join(duplicates, ", "))
+	}
+
+	return nil
+}
+
+// indirectType returns the type that the given pointer type points to.
+func indirectType(valueType reflect.Type) reflect.Type {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	return valueType
+}
+
+func checkEnumValue(stringValue string, enum []string) error {
+	if enum == nil || slices.Contains(enum, stringValue) {
+		return nil
+	}
+
+	return fmt.Errorf("invalid value %q, allowed values are %s", stringValue, strings.Join(enum, ", "))
+}
+
+// transformValue applies the given transforms to the value in order.
+func transformValue(stringValue string, transforms []string) string {
+	for _, transform := range transforms {
+		switch transform {
+		case TransformLower:
+			stringValue = strings.ToLower(stringValue)
+
+		case TransformUpper:
+			stringValue = strings.ToUpper(stringValue)
+		}
+	}
+
+	return stringValue
+}
 
-type columnInfo struct {
-	Header      string
-	ColumnIndex int
-	FieldIndex  int
-	Format      string
+// Read rows from reader and append them to the rows slice. The rows slice is
+// left unchanged if the read fails.
+func ReadRowsFromReader(reader io.Reader, hasHeader bool, rows interface{}) error {
+	return ReadRowsFromReaderWithOptions(reader, hasHeader, rows, Options{})
 }
 
-func setBoolValue(value reflect.Value, stringValue string) error {
-	actualValue, err := strconv.ParseBool(stringValue)
-	if err == nil {
-		value.SetBool(actualValue)
-	}
+// Read rows from reader with options.
+func ReadRowsFromReaderWithOptions(reader io.Reader, hasHeader bool, rows interface{}, options Options) error {
+	_, err := readRowsFromReader(context.Background(), reader, hasHeader, rows, options)
+	return err
+}
 
+// Read rows from reader until the context is done.
+func ReadRowsFromReaderContext(ctx context.Context, reader io.Reader, hasHeader bool, rows interface{}) error {
+	_, err := readRowsFromReader(ctx, reader, hasHeader, rows, Options{})
 	return err
 }
 
-func setIntValue(value reflect.Value, stringValue string, bitSize int) error {
-	actualValue, err := strconv.ParseInt(stringValue, 10, bitSize)
-	if err == nil {
-		value.SetInt(actualValue)
-	}
+// Read rows from reader with functional options.
+func ReadRowsFromReaderWith(reader io.Reader, hasHeader bool, rows interface{}, opts ...Option) error {
+	return ReadRowsFromReaderWithOptions(reader, hasHeader, rows, newOptions(opts))
+}
 
-	return err
+// Read rows from reader and return the number of rows read.
+func ReadRowsFromReaderN(reader io.Reader, hasHeader bool, rows interface{}) (int, error) {
+	return readRowsFromReader(context.Background(), reader, hasHeader, rows, Options{})
 }
 
-func setUintValue(value reflect.Value, stringValue string, bitSize int) error {
-	actualValue, err := strconv.ParseUint(stringValue, 10, bitSize)
-	if err == nil {
-		value.SetUint(actualValue)
+func getRowType(rows interface{}) (reflect.Type, error) {
+	rowsPtrType := reflect.TypeOf(rows)
+	if rowsPtrType == nil || rowsPtrType.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("rows %w", ErrNotPointer)
 	}
 
-	return err
-}
+	rowsSliceType := rowsPtrType.Elem()
+	if rowsSliceType.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("rows %w", ErrNotSlice)
+	}
 
-func setFloatValue(value reflect.Value, stringValue string, bitSize int) error {
-	actualValue, err := strconv.ParseFloat(stringValue, bitSize)
-	if err == nil {
-		value.SetFloat(actualValue)
+	rowType := rowsSliceType.Elem()
+	if rowType.Kind() == reflect.Ptr {
+		rowType = rowType.Elem()
 	}
 
-	return err
+	if rowType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rows element %w", ErrNotStruct)
+	}
+
+	return rowType, nil
 }
 
-func setTimeValue(value reflect.Value, stringValue string, format string) error {
-	actualValue, err := time.Parse(format, stringValue)
-	if err == nil {
-		value.Set(reflect.ValueOf(actualValue))
+func readRowsFromReader(ctx context.Context, reader io.Reader, hasHeader bool, rows interface{}, options Options) (int, error) {
+	rowType, err := getRowType(rows)
+	if err != nil {
+		return 0, err
 	}
 
-	return err
-}
+	columns, err := getStructFieldsAsColumns(rowType, unmarshalerType)
+	if err != nil {
+		return 0, err
+	}
 
-func setValue(value reflect.Value, stringValue string, format string) error {
-	kind := value.Kind()
+	recordReader, err := newRecordReader(ctx, reader, hasHeader, columns, &options)
+	if err != nil {
+		return 0, err
+	}
 
-	switch kind {
-	case reflect.String:
-		value.SetString(stringValue)
-		return nil
+	rowsSlice := reflect.ValueOf(rows).Elem()
+	length := rowsSlice.Len()
 
-	case reflect.Bool:
-		return setBoolValue(value, stringValue)
+	n, err := recordReader.readRows(rowsSlice)
+	if err != nil {
+		truncateRows(rowsSlice, length)
+		return 0, err
+	}
 
-	case reflect.Int:
-		return setIntValue(value, stringValue, bits.UintSize)
+	return n, nil
+}
 
-	case reflect.Int8:
-		return setIntValue(value, stringValue, 8)
+// truncateRows drops the rows appended after the given length, so that a
+// failed read leaves the rows slice as it was.
+func truncateRows(rowsSlice reflect.Value, length int) {
+	rowsSlice.Slice(length, rowsSlice.Len()).Clear()
+	rowsSlice.SetLen(length)
+}
 
-	case reflect.Int16:
-		return setIntValue(value, stringValue, 16)
+func getRowTemplateType(rowTemplate interface{}) (reflect.Type, error) {
+	rowPtrType := reflect.TypeOf(rowTemplate)
+	if rowPtrType == nil || rowPtrType.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("row template %w", ErrNotPointer)
+	}
 
-	case reflect.Int32:
-		return setIntValue(value, stringValue, 32)
+	rowType := rowPtrType.Elem()
+	if rowType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("row template %w", ErrNotStruct)
+	}
 
-	case reflect.Int64:
-		return setIntValue(value, stringValue, 64)
+	return rowType, nil
+}
 
-	case reflect.Uint:
-		return setUintValue(value, stringValue, bits.UintSize)
+// Read the first row in reader into the given row. It returns io.EOF if
+// there is no data row.
+func ReadRowFromReader(reader io.Reader, hasHeader bool, row interface{}) error {
+	rowType, err := getRowTemplateType(row)
+	if err != nil {
+		return err
+	}
 
-	case reflect.Uint8:
-		return setUintValue(value, stringValue, 8)
+	columns, err := getStructFieldsAsColumns(rowType, unmarshalerType)
+	if err != nil {
+		return err
+	}
 
-	case reflect.Uint16:
-		return setUintValue(value, stringValue, 16)
+	options := Options{}
 
-	case reflect.Uint32:
-		return setUintValue(value, stringValue, 32)
+	recordReader, err := newRecordReader(context.Background(), reader, hasHeader, columns, &options)
+	if err != nil {
+		return err
+	}
 
-	case reflect.Uint64:
-		return setUintValue(value, stringValue, 64)
+	return recordReader.readRow(reflect.ValueOf(row).Elem())
+}
 
-	case reflect.Float32:
-		return setFloatValue(value, stringValue, 32)
+// For each row in reader, read it into the row template and call the given
+// function. Reading stops at the first error returned by the function.
+func ForEachRow(reader io.Reader, hasHeader bool, rowTemplate interface{}, fn func(interface{}) error) error {
+	rowType, err := getRowTemplateType(rowTemplate)
+	if err != nil {
+		return err
+	}
 
-	case reflect.Float64:
-		return setFloatValue(value, stringValue, 64)
+	row := reflect.ValueOf(rowTemplate).Elem()
 
-	case reflect.Struct:
-		typeString := value.Type().String()
+	columns, err := getStructFieldsAsColumns(rowType, unmarshalerType)
+	if err != nil {
+		return err
+	}
 
-		switch typeString {
-		case "time.Time":
-			return setTimeValue(value, stringValue, format)
+	options := Options{}
 
-		default:
-			return fmt.Errorf("unsupported struct type %s", typeString)
+	recordReader, err := newRecordReader(context.Background(), reader, hasHeader, columns, &options)
+	if err != nil {
+		return err
+	}
+
+	for {
+		err = recordReader.readRow(row)
+		if err == io.EOF {
+			break
 		}
 
-	default:
-		return fmt.Errorf("unsupported value kind %s", kind)
+		if err != nil {
+			return err
+		}
+
+		if err = fn(rowTemplate); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
-func getStructFieldsAsColumns(structType reflect.Type) []columnInfo {
-	columns := make([]columnInfo, structType.NumField())
-	for i := 0; i < structType.NumField(); i++ {
-		field := structType.Field(i)
+// Rows returns an iterator over the rows of the given type in reader. The
+// iteration stops after yielding the first error.
+func Rows[T any](reader io.Reader, hasHeader bool) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var row T
 
-		header, ok := field.Tag.Lookup(TagHeader)
-		if !ok {
-			header = field.Name
+		rowType := reflect.TypeOf((*T)(nil)).Elem()
+		if rowType.Kind() != reflect.Struct {
+			yield(row, fmt.Errorf("row %w", ErrNotStruct))
+			return
 		}
 
-		format, ok := field.Tag.Lookup(TagFormat)
-		if !ok {
-			format = timeFormat
+		columns, err := getStructFieldsAsColumns(rowType, unmarshalerType)
+		if err != nil {
+			yield(row, err)
+			return
 		}
 
-		columns[i] = columnInfo{
-			Header:      header,
-			ColumnIndex: i,
-			FieldIndex:  i,
-			Format:      format,
+		options := Options{}
+
+		recordReader, err := newRecordReader(context.Background(), reader, hasHeader, columns, &options)
+		if err != nil {
+			yield(row, err)
+			return
+		}
+
+		for {
+			var row T
+
+			err = recordReader.readRow(reflect.ValueOf(&row).Elem())
+			if err == io.EOF {
+				return
+			}
+
+			if !yield(row, err) || err != nil {
+				return
+			}
 		}
 	}
+}
 
-	return columns
+// Read the header of reader.
+func ReadHeaders(reader io.Reader) ([]string, error) {
+	return ReadHeadersWithOptions(reader, Options{})
 }
 
-func readHeader(csvReader csv.Reader, columns []columnInfo) error {
-	headers, err := csvReader.Read()
+// Read the header of reader with options.
+func ReadHeadersWithOptions(reader io.Reader, options Options) ([]string, error) {
+	return readHeader(newCsvReader(reader, options), &options)
+}
+
+// Resolve the columns of the row template against the header of reader,
+// without reading any data records.
+func ResolveColumns(reader io.Reader, rowTemplate interface{}) ([]Column, error) {
+	return ResolveColumnsWithOptions(reader, rowTemplate, Options{})
+}
+
+// Resolve the columns of the row template against the header of reader with
+// options, without reading any data records.
+func ResolveColumnsWithOptions(reader io.Reader, rowTemplate interface{}, options Options) ([]Column, error) {
+	rowType, err := getRowTemplateType(rowTemplate)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, column := range columns {
-		for i, header := range headers {
-			if strings.EqualFold(column.Header, header) {
-				column.ColumnIndex = i
-				break
-			}
-		}
+	columns, err := getStructFieldsAsColumns(rowType, unmarshalerType)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	recordReader, err := newRecordReader(context.Background(), reader, true, columns, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	return recordReader.columns, nil
 }
 
-// Read rows from reader.
-func ReadRowsFromReader(reader io.Reader, hasHeader bool, rows interface{}) error {
-	rowsPtrType := reflect.TypeOf(rows)
-	if rowsPtrType.Kind() != reflect.Ptr {
-		return errors.New("rows not a pointer")
+// Check that the header of reader has exactly the columns of the row template,
+// in any order. The error lists the missing and the unexpected columns.
+func CheckHeaders(reader io.Reader, rowTemplate interface{}) error {
+	rowType, err := getRowTemplateType(rowTemplate)
+	if err != nil {
+		return err
 	}
 
-	rowsSliceType := rowsPtrType.Elem()
-	if rowsSliceType.Kind() != reflect.Slice {
-		return errors.New("rows not a pointer to slice")
+	columns, err := getStructFieldsAsColumns(rowType, unmarshalerType)
+	if err != nil {
+		return err
 	}
 
-	rowType := rowsSliceType.Elem()
-	if rowType.Kind() != reflect.Struct {
-		return errors.New("rows not a pointer to slice of struct")
+	options := Options{}
+
+	recordReader, err := newRecordReader(context.Background(), reader, true, columns, &options)
+	if err != nil {
+		return err
 	}
 
-	rowsPtr := reflect.ValueOf(rows)
-	rowsSlice := rowsPtr.Elem()
+	var problems []string
 
-	columns := getStructFieldsAsColumns(rowType)
+	if missing := missingColumns(recordReader.columns); len(missing) > 0 {
+		problems = append(problems, "missing columns: "+strings.Join(missing, ", "))
+	}
 
-	csvReader := csv.NewReader(reader)
+	if unknown := unknownHeaders(recordReader.headers, recordReader.columns); len(unknown) > 0 {
+		problems = append(problems, "unexpected columns: "+strings.Join(unknown, ", "))
+	}
 
-	if hasHeader {
-		if err := readHeader(*csvReader, columns); err != nil {
-			return err
-		}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
 	}
 
-	for {
-		record, err := csvReader.Read()
-		if err == io.EOF {
-			break
+	return nil
+}
+
+// missingColumns returns the headers of the columns not found in the header.
+func missingColumns(columns []Column) []string {
+	var missing []string
+
+	for _, column := range columns {
+		if column.ColumnIndex < 0 {
+			missing = append(missing, column.Header)
 		}
+	}
 
-		if err != nil {
-			return err
+	return missing
+}
+
+// unknownHeaders returns the headers not mapped to any of the columns.
+func unknownHeaders(headers []string, columns []Column) []string {
+	mapped := make([]bool, len(headers))
+
+	for _, column := range columns {
+		if column.ColumnIndex >= 0 && column.ColumnIndex < len(headers) {
+			mapped[column.ColumnIndex] = true
 		}
+	}
 
-		row := reflect.New(rowType).Elem()
+	var unknown []string
 
-		for _, column := range columns {
-			if err = setValue(row.Field(column.FieldIndex), record[column.ColumnIndex], column.Format); err != nil {
-				return err
-			}
+	for i, header := range headers {
+		if !mapped[i] {
+			unknown = append(unknown, header)
 		}
+	}
+
+	return unknown
+}
 
-		rowsSlice = reflect.Append(rowsSlice, row)
+// Read rows from string.
+func ReadRowsFromString(data string, hasHeader bool, rows interface{}) error {
+	return ReadRowsFromReader(strings.NewReader(data), hasHeader, rows)
+}
+
+// Read rows from bytes.
+func ReadRowsFromBytes(data []byte, hasHeader bool, rows interface{}) error {
+	return ReadRowsFromReader(bytes.NewReader(data), hasHeader, rows)
+}
+
+// Read rows of the given type from reader.
+func ReadRows[T any](reader io.Reader, hasHeader bool) ([]T, error) {
+	var rows []T
+
+	if err := ReadRowsFromReader(reader, hasHeader, &rows); err != nil {
+		return nil, err
 	}
 
-	rowsPtr.Elem().Set(rowsSlice)
+	return rows, nil
+}
 
-	return nil
+// gzipFile is a gzip reader that closes its underlying file.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
 }
 
-// Read rows from file.
-func ReadRowsFromFile(fileName string, hasHeader bool, rows interface{}) error {
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// openFile opens the given file for reading, decompressing it if its name
+// ends with the .gz extension.
+func openFile(fileName string) (io.ReadCloser, error) {
 	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(filepath.Ext(fileName), gzipExtension) {
+		return file, nil
+	}
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &gzipFile{Reader: gzipReader, file: file}, nil
+}
+
+// Read rows from file. The file is decompressed if its name ends with .gz.
+func ReadRowsFromFile(fileName string, hasHeader bool, rows interface{}) error {
+	file, err := openFile(fileName)
 	if err != nil {
 		return err
 	}
@@ -252,36 +1457,63 @@ func ReadRowsFromFile(fileName string, hasHeader bool, rows interface{}) error {
 	return ReadRowsFromReader(file, hasHeader, rows)
 }
 
-// Read table from reader.
-func ReadTableFromReader(reader io.Reader, hasHeader bool, table interface{}) error {
-	tablePtrType := reflect.TypeOf(table)
-	if tablePtrType.Kind() != reflect.Ptr {
-		return errors.New("table not a pointer")
+// Read rows from the files in order and append them all to the same slice. The
+// header of each file is used for its own column mapping. The errors are
+// prefixed with the name of the file that failed, and the rows of the files
+// before it are kept.
+func ReadRowsFromFiles(fileNames []string, hasHeader bool, rows interface{}) error {
+	for _, fileName := range fileNames {
+		if err := ReadRowsFromFile(fileName, hasHeader, rows); err != nil {
+			return fmt.Errorf("%s: %w", fileName, err)
+		}
 	}
 
-	tableType := tablePtrType.Elem()
-	if tableType.Kind() != reflect.Struct {
-		return errors.New("table not a pointer to struct")
+	return nil
+}
+
+// Count the data rows in reader without parsing them.
+func CountRows(reader io.Reader, hasHeader bool) (int, error) {
+	return CountRowsWithOptions(reader, hasHeader, Options{})
+}
+
+// Count the data rows in reader with options without parsing them. The
+// Comment, Skip, and Limit options are honored, so the count matches the
+// number of rows a read with the same options produces.
+func CountRowsWithOptions(reader io.Reader, hasHeader bool, options Options) (int, error) {
+	recordReader, err := newRecordReader(context.Background(), reader, hasHeader, nil, &options)
+	if err != nil {
+		return 0, err
 	}
 
-	for i := 0; i < tableType.NumField(); i++ {
-		if tableType.Field(i).Type.Kind() != reflect.Slice {
-			return errors.New("table fields must be all slices")
+	n := 0
+
+	for {
+		err := recordReader.read(func(record []string) error {
+			return nil
+		})
+		if err == io.EOF {
+			return n, nil
 		}
-	}
 
-	tableValue := reflect.ValueOf(table).Elem()
+		if err != nil {
+			return n, err
+		}
 
-	columns := getStructFieldsAsColumns(tableType)
+		n++
+	}
+}
 
-	csvReader := csv.NewReader(reader)
+// Read records as maps keyed by the header.
+func ReadRecordsAsMaps(reader io.Reader) ([]map[string]string, error) {
+	csvReader := newCsvReader(reader, Options{})
 
-	if hasHeader {
-		if err := readHeader(*csvReader, columns); err != nil {
-			return err
-		}
+	headers, err := readHeader(csvReader, &Options{})
+	if err != nil {
+		return nil, err
 	}
 
+	var records []map[string]string
+
 	for {
 		record, err := csvReader.Read()
 		if err == io.EOF {
@@ -289,27 +1521,63 @@ func ReadTableFromReader(reader io.Reader, hasHeader bool, table interface{}) er
 		}
 
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		recordMap := make(map[string]string, len(headers))
+		for i, header := range headers {
+			recordMap[header] = record[i]
 		}
 
-		for _, column := range columns {
-			sliceValue := tableValue.Field(column.FieldIndex)
+		records = append(records, recordMap)
+	}
 
-			itemValue := reflect.New(sliceValue.Type().Elem()).Elem()
-			if err = setValue(itemValue, record[column.ColumnIndex], column.Format); err != nil {
-				return err
-			}
+	return records, nil
+}
 
-			sliceValue.Set(reflect.Append(sliceValue, itemValue))
-		}
+// Read table from reader.
+func ReadTableFromReader(reader io.Reader, hasHeader bool, table interface{}) error {
+	return ReadTableFromReaderWithOptions(reader, hasHeader, table, Options{})
+}
+
+// Read table from reader with options.
+func ReadTableFromReaderWithOptions(reader io.Reader, hasHeader bool, table interface{}, options Options) error {
+	_, err := readTableFromReader(reader, hasHeader, table, options)
+	return err
+}
+
+// Read table from reader and return the number of rows read.
+func ReadTableFromReaderN(reader io.Reader, hasHeader bool, table interface{}) (int, error) {
+	return readTableFromReader(reader, hasHeader, table, Options{})
+}
+
+func readTableFromReader(reader io.Reader, hasHeader bool, table interface{}, options Options) (int, error) {
+	tablePtrType := reflect.TypeOf(table)
+	if tablePtrType == nil || tablePtrType.Kind() != reflect.Ptr {
+		return 0, fmt.Errorf("table %w", ErrNotPointer)
 	}
 
-	return nil
+	tableType := tablePtrType.Elem()
+	if tableType.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("table %w", ErrNotStruct)
+	}
+
+	columns, err := getTableFieldsAsColumns(tableType, unmarshalerType)
+	if err != nil {
+		return 0, err
+	}
+
+	recordReader, err := newRecordReader(context.Background(), reader, hasHeader, columns, &options)
+	if err != nil {
+		return 0, err
+	}
+
+	return recordReader.readTable(reflect.ValueOf(table).Elem())
 }
 
-// Read table from file.
+// Read table from file. The file is decompressed if its name ends with .gz.
 func ReadTableFromFile(fileName string, hasHeader bool, rows interface{}) error {
-	file, err := os.Open(fileName)
+	file, err := openFile(fileName)
 	if err != nil {
 		return err
 	}
@@ -318,3 +1586,13 @@ func ReadTableFromFile(fileName string, hasHeader bool, rows interface{}) error
 
 	return ReadTableFromReader(file, hasHeader, rows)
 }
+
+// Read table from string.
+func ReadTableFromString(data string, hasHeader bool, table interface{}) error {
+	return ReadTableFromReader(strings.NewReader(data), hasHeader, table)
+}
+
+// Read table from bytes.
+func ReadTableFromBytes(data []byte, hasHeader bool, table interface{}) error {
+	return ReadTableFromReader(bytes.NewReader(data), hasHeader, table)
+}
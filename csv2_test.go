@@ -1,6 +1,21 @@
 package csv2
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -58,6 +73,43 @@ func TestReadRowsFromFile(t *testing.T) {
 	}
 }
 
+func TestReadRowsFromFiles(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	dir := t.TempDir()
+
+	fileNames := []string{filepath.Join(dir, "1.csv"), filepath.Join(dir, "2.csv"), filepath.Join(dir, "3.csv")}
+	contents := []string{"name,volume\na,1\nb,2\n", "volume,name\n3,c\n", "name,volume\nd,x\n"}
+
+	for i, fileName := range fileNames {
+		if err := os.WriteFile(fileName, []byte(contents[i]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var rows []row
+
+	err := ReadRowsFromFiles(fileNames[:2], true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []row{{"a", 1}, {"b", 2}, {"c", 3}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("actual %v must be %v", rows, expected)
+	}
+
+	err = ReadRowsFromFiles(fileNames, true, &rows)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || !strings.HasPrefix(err.Error(), fileNames[2]+": ") {
+		t.Fatalf("expected parse error for %s but got %v", fileNames[2], err)
+	}
+}
+
 func TestReadTableFromFile(t *testing.T) {
 	prices := stockPrices{}
 
@@ -70,3 +122,1583 @@ func TestReadTableFromFile(t *testing.T) {
 		t.Fatalf("date must have 10 elements but has %d", n)
 	}
 }
+
+func TestReadRowsFromFileGzip(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "test.csv.gz")
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gzipWriter := gzip.NewWriter(file)
+
+	if _, err := gzipWriter.Write([]byte(testData(t))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var prices []dailyPrice
+
+	err = ReadRowsFromFile(fileName, true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 10 {
+		t.Fatalf("prices must have 10 element but has %d", n)
+	}
+
+	table := stockPrices{}
+
+	err = ReadTableFromFile(fileName, true, &table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(table.Date); n != 10 {
+		t.Fatalf("date must have 10 elements but has %d", n)
+	}
+
+	err = ReadRowsFromFile(testFile+gzipExtension, true, &prices)
+	if err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestReadRowsFromReaderSkipField(t *testing.T) {
+	type person struct {
+		Name     string `header:"name"`
+		Computed int    `header:"-"`
+		Age      int    `header:"age"`
+	}
+
+	var people []person
+
+	err := ReadRowsFromReader(strings.NewReader("age,name\n42,alice\n"), true, &people)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(people); n != 1 {
+		t.Fatalf("people must have 1 element but has %d", n)
+	}
+
+	if people[0].Name != "alice" || people[0].Age != 42 || people[0].Computed != 0 {
+		t.Fatalf("unexpected person %v", people[0])
+	}
+}
+
+func TestReadRowsFromReaderPointerFields(t *testing.T) {
+	type row struct {
+		Name  *string
+		Price *float64
+		Date  *time.Time `format:"2006-01-02"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("Name,Price,Date\na,,2021-01-02\n,1.5,\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Name == nil || *rows[0].Name != "a" || rows[0].Price != nil || rows[0].Date == nil {
+		t.Fatalf("unexpected first row %v", rows[0])
+	}
+
+	if rows[1].Name != nil || rows[1].Price == nil || *rows[1].Price != 1.5 || rows[1].Date != nil {
+		t.Fatalf("unexpected second row %v", rows[1])
+	}
+}
+
+// Money amount in cents.
+type money int64
+
+func (m *money) UnmarshalCSV(stringValue string) error {
+	stringValue = strings.ReplaceAll(strings.TrimPrefix(stringValue, "$"), ",", "")
+
+	parts := strings.SplitN(stringValue, ".", 2)
+	if len(parts) != 2 || len(parts[1]) != 2 {
+		return errors.New("invalid money amount")
+	}
+
+	amount, err := strconv.ParseInt(parts[0]+parts[1], 10, 64)
+	if err == nil {
+		*m = money(amount)
+	}
+
+	return err
+}
+
+func TestReadRowsFromReaderUnmarshaler(t *testing.T) {
+	type row struct {
+		Name     string
+		Amount   money
+		Optional *money
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("a,\"$1,234.56\",$1.00\nb,$0.10,\n"), false, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Amount != 123456 || rows[0].Optional == nil || *rows[0].Optional != 100 {
+		t.Fatalf("unexpected first row %v", rows[0])
+	}
+
+	if rows[1].Amount != 10 || rows[1].Optional != nil {
+		t.Fatalf("unexpected second row %v", rows[1])
+	}
+}
+
+func TestReadTableFromReaderUnmarshaler(t *testing.T) {
+	table := struct {
+		Name   []string
+		Amount []money
+	}{}
+
+	err := ReadTableFromReader(strings.NewReader("a,$1.50\nb,$2.00\n"), false, &table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if table.Amount[0] != 150 || table.Amount[1] != 200 {
+		t.Fatalf("unexpected amounts %v", table.Amount)
+	}
+}
+
+func TestReadRecordsAsMaps(t *testing.T) {
+	records, err := ReadRecordsAsMaps(strings.NewReader("name,age\nalice,42\nbob,7\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(records); n != 2 {
+		t.Fatalf("records must have 2 elements but has %d", n)
+	}
+
+	if records[0]["name"] != "alice" || records[0]["age"] != "42" || records[1]["name"] != "bob" {
+		t.Fatalf("unexpected records %v", records)
+	}
+}
+
+func TestReadRows(t *testing.T) {
+	type person struct {
+		Name string `header:"name"`
+		Age  int    `header:"age"`
+	}
+
+	people, err := ReadRows[person](strings.NewReader("name,age\nalice,42\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(people); n != 1 {
+		t.Fatalf("people must have 1 element but has %d", n)
+	}
+
+	if people[0].Name != "alice" || people[0].Age != 42 {
+		t.Fatalf("unexpected person %v", people[0])
+	}
+}
+
+func TestForEachRow(t *testing.T) {
+	var price dailyPrice
+
+	count := 0
+	total := 0.0
+
+	err := ForEachRow(strings.NewReader(testData(t)), true, &price, func(row interface{}) error {
+		count++
+		total += row.(*dailyPrice).Close
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 10 {
+		t.Fatalf("count must be 10 but is %d", count)
+	}
+
+	if total < 438.7 || total > 438.8 {
+		t.Fatalf("unexpected total %f", total)
+	}
+}
+
+func TestForEachRowStop(t *testing.T) {
+	var price dailyPrice
+
+	stop := errors.New("stop")
+	count := 0
+
+	err := ForEachRow(strings.NewReader(testData(t)), true, &price, func(row interface{}) error {
+		count++
+		if count == 3 {
+			return stop
+		}
+
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("expected stop error but got %v", err)
+	}
+
+	if count != 3 {
+		t.Fatalf("count must be 3 but is %d", count)
+	}
+}
+
+func testData(t *testing.T) string {
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestReadRowsFromReaderUnixTime(t *testing.T) {
+	type event struct {
+		Seconds time.Time `format:"unix"`
+		Millis  time.Time `format:"unixmilli"`
+	}
+
+	var events []event
+
+	err := ReadRowsFromReader(strings.NewReader("1609459200,1609459200123\n"), false, &events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !events[0].Seconds.Equal(expected) {
+		t.Fatalf("seconds %v must be %v", events[0].Seconds, expected)
+	}
+
+	expected = expected.Add(123 * time.Millisecond)
+	if !events[0].Millis.Equal(expected) {
+		t.Fatalf("millis %v must be %v", events[0].Millis, expected)
+	}
+
+	err = ReadRowsFromReader(strings.NewReader(",1\n"), false, &events)
+	if err == nil {
+		t.Fatal("expected error for empty unix time")
+	}
+}
+
+func TestReadRowsFromReaderExcelTime(t *testing.T) {
+	type event struct {
+		Date time.Time `format:"excel"`
+	}
+
+	var events []event
+
+	err := ReadRowsFromReader(strings.NewReader("44197\n44197.75\n1\n"), false, &events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []time.Time{
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 18, 0, 0, 0, time.UTC),
+		time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	for i, date := range expected {
+		if !events[i].Date.Equal(date) {
+			t.Fatalf("date %v must be %v", events[i].Date, date)
+		}
+	}
+
+	err = ReadRowsFromReader(strings.NewReader("2021-01-01\n"), false, &events)
+	if err == nil {
+		t.Fatal("expected error for invalid serial date")
+	}
+}
+
+func TestReadRowsFromReaderDuration(t *testing.T) {
+	type interval struct {
+		Name     string
+		Duration time.Duration
+	}
+
+	var intervals []interval
+
+	err := ReadRowsFromReader(strings.NewReader("a,1h30m\nb,250ms\n"), false, &intervals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if intervals[0].Duration != 90*time.Minute || intervals[1].Duration != 250*time.Millisecond {
+		t.Fatalf("unexpected intervals %v", intervals)
+	}
+}
+
+func TestReadRowsFromReaderIndexTag(t *testing.T) {
+	type row struct {
+		Date  string
+		Close float64 `index:"2"`
+		Open  float64 `index:"1"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("2021-01-02,1.5,2.5\n"), false, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Date != "2021-01-02" || rows[0].Close != 2.5 || rows[0].Open != 1.5 {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderInvalidIndexTag(t *testing.T) {
+	type row struct {
+		Close float64 `index:"x"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("1\n"), false, &rows)
+	if err == nil {
+		t.Fatal("expected error for invalid index")
+	}
+}
+
+func TestReadRowsFromReaderIndexTagWithHeader(t *testing.T) {
+	type row struct {
+		A int `index:"1"`
+		B int
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("A,B\n1,2\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].A != 1 || rows[0].B != 2 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+// Auditable columns shared by rows.
+type auditable struct {
+	CreatedAt time.Time `header:"created_at" format:"2006-01-02"`
+	UpdatedAt time.Time `header:"updated_at" format:"2006-01-02"`
+}
+
+func TestReadRowsFromReaderEmbeddedStruct(t *testing.T) {
+	type user struct {
+		Name string `header:"name"`
+		auditable
+	}
+
+	var users []user
+
+	err := ReadRowsFromReader(strings.NewReader("updated_at,name,created_at\n2021-02-03,alice,2021-01-02\n"), true, &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if users[0].Name != "alice" {
+		t.Fatalf("name %q must be alice", users[0].Name)
+	}
+
+	if expected := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC); !users[0].CreatedAt.Equal(expected) {
+		t.Fatalf("created at %v must be %v", users[0].CreatedAt, expected)
+	}
+
+	if expected := time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC); !users[0].UpdatedAt.Equal(expected) {
+		t.Fatalf("updated at %v must be %v", users[0].UpdatedAt, expected)
+	}
+}
+
+func TestReadRowsFromReaderContext(t *testing.T) {
+	var prices []dailyPrice
+
+	err := ReadRowsFromReaderContext(context.Background(), strings.NewReader(testData(t)), true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 10 {
+		t.Fatalf("prices must have 10 element but has %d", n)
+	}
+}
+
+func TestReadRowsFromReaderContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var prices []dailyPrice
+
+	err := ReadRowsFromReaderContext(ctx, strings.NewReader(testData(t)), true, &prices)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderIntegerBase(t *testing.T) {
+	type row struct {
+		Hex    int   `format:"base=16"`
+		Binary uint8 `format:"base=2"`
+		Auto   int64 `format:"base=0"`
+		Plain  uint16
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("0x1F,0b101,0o17,42\n-1f,11,0x10,7\n"), false, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Hex != 31 || rows[0].Binary != 5 || rows[0].Auto != 15 || rows[0].Plain != 42 {
+		t.Fatalf("unexpected first row %v", rows[0])
+	}
+
+	if rows[1].Hex != -31 || rows[1].Binary != 3 || rows[1].Auto != 16 || rows[1].Plain != 7 {
+		t.Fatalf("unexpected second row %v", rows[1])
+	}
+}
+
+func TestGetStructFieldsAsColumnsCache(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("volume,name\n1,a\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns, err := getStructFieldsAsColumns(reflect.TypeOf(row{}), unmarshalerType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if columns[0].ColumnIndex != 0 || columns[1].ColumnIndex != 1 {
+		t.Fatalf("cached columns modified by header mapping %v", columns)
+	}
+}
+
+func BenchmarkReadRowsFromReader(b *testing.B) {
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		var prices []dailyPrice
+
+		err := ReadRowsFromReader(bytes.NewReader(data), true, &prices)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReadRowsFromString(t *testing.T) {
+	var prices []dailyPrice
+
+	err := ReadRowsFromString(testData(t), true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 10 {
+		t.Fatalf("prices must have 10 element but has %d", n)
+	}
+}
+
+func TestReadRowsFromBytes(t *testing.T) {
+	var prices []dailyPrice
+
+	err := ReadRowsFromBytes([]byte(testData(t)), true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 10 {
+		t.Fatalf("prices must have 10 element but has %d", n)
+	}
+}
+
+func TestReadTableFromString(t *testing.T) {
+	prices := stockPrices{}
+
+	err := ReadTableFromString(testData(t), true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices.Date); n != 10 {
+		t.Fatalf("date must have 10 elements but has %d", n)
+	}
+}
+
+func TestReadTableFromBytes(t *testing.T) {
+	prices := stockPrices{}
+
+	err := ReadTableFromBytes([]byte(testData(t)), true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices.Date); n != 10 {
+		t.Fatalf("date must have 10 elements but has %d", n)
+	}
+}
+
+func TestReadRowsFromReaderN(t *testing.T) {
+	prices := make([]dailyPrice, 5)
+
+	n, err := ReadRowsFromReaderN(strings.NewReader(testData(t)), true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 10 {
+		t.Fatalf("n must be 10 but is %d", n)
+	}
+
+	if len(prices) != 15 {
+		t.Fatalf("prices must have 15 elements but has %d", len(prices))
+	}
+}
+
+func TestReadRowsFromReaderErrorKeepsRows(t *testing.T) {
+	type row struct {
+		ID int
+	}
+
+	rows := []row{{99}}
+
+	n, err := ReadRowsFromReaderN(strings.NewReader("1\n2\nx\n"), false, &rows)
+	if err == nil {
+		t.Fatal("expected parse error")
+	}
+
+	if n != 0 {
+		t.Fatalf("n must be 0 but is %d", n)
+	}
+
+	if !reflect.DeepEqual(rows, []row{{99}}) {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadTableFromReaderN(t *testing.T) {
+	prices := stockPrices{}
+
+	n, err := ReadTableFromReaderN(strings.NewReader(testData(t)), true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 10 {
+		t.Fatalf("n must be 10 but is %d", n)
+	}
+}
+
+func TestReadRowsFromReaderBom(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("\xEF\xBB\xBFvolume,name\n1,a\n"), true, &rows, Options{RequireAllColumns: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Name != "a" || rows[0].Volume != 1 {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderDuplicateHeaders(t *testing.T) {
+	type quote struct {
+		Symbol string  `header:"Symbol"`
+		Bid    float64 `header:"Price"`
+		Ask    float64 `header:"Price#2"`
+	}
+
+	data := "Symbol,Price,Price\nA,1.5,1.75\n"
+
+	var quotes []quote
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(data), true, &quotes, Options{RequireAllColumns: true})
+	if err == nil || err.Error() != "duplicate columns in header: Price" {
+		t.Fatalf("expected duplicate column error but got %v", err)
+	}
+
+	err = ReadRowsFromReader(strings.NewReader(data), true, &quotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if quotes[0].Bid != 1.5 || quotes[0].Ask != 1.75 {
+		t.Fatalf("unexpected quote %v", quotes[0])
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, true, quotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := buffer.String(); actual != data {
+		t.Fatalf("actual %q must be %q", actual, data)
+	}
+}
+
+func TestReadRowsFromReaderLiteralOccurrenceHeader(t *testing.T) {
+	type contact struct {
+		Name   string `header:"Name"`
+		Phone  string `header:"Phone #1"`
+		Phone2 string `header:"Phone #2"`
+	}
+
+	data := "Name,Phone #1,Phone #2\na,123,456\n"
+
+	var contacts []contact
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(data), true, &contacts, Options{RequireAllColumns: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contacts[0].Phone != "123" || contacts[0].Phone2 != "456" {
+		t.Fatalf("unexpected contact %v", contacts[0])
+	}
+
+	type literal struct {
+		Phone string `header:"Phone #1"`
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, true, []literal{{Phone: "123"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "Phone #1\n123\n"; buffer.String() != expected {
+		t.Fatalf("actual %q must be %q", buffer.String(), expected)
+	}
+}
+
+func TestReadRowsFromReaderIPAndURL(t *testing.T) {
+	type access struct {
+		Address net.IP
+		Page    *url.URL
+		Referer url.URL
+	}
+
+	var accesses []access
+
+	err := ReadRowsFromReader(strings.NewReader("192.168.1.1,https://example.com/a?b=c,http://example.org\n::1,,/\n"), false, &accesses)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !accesses[0].Address.Equal(net.IPv4(192, 168, 1, 1)) || accesses[0].Page.Host != "example.com" || accesses[0].Page.Path != "/a" {
+		t.Fatalf("unexpected first access %v", accesses[0])
+	}
+
+	if !accesses[1].Address.Equal(net.IPv6loopback) || accesses[1].Page != nil || accesses[1].Referer.Path != "/" {
+		t.Fatalf("unexpected second access %v", accesses[1])
+	}
+
+	err = ReadRowsFromReader(strings.NewReader("999.1.1.1,,\n"), false, &accesses)
+	if err == nil || !strings.Contains(err.Error(), `invalid IP address "999.1.1.1"`) {
+		t.Fatalf("expected invalid IP error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderDefaultTag(t *testing.T) {
+	type row struct {
+		Name   string  `default:"N/A"`
+		Price  float64 `default:"1.5"`
+		Volume *int    `default:"7"`
+		Count  int
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader(",,,0\na,2,3,4\n"), false, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Name != "N/A" || rows[0].Price != 1.5 || rows[0].Volume == nil || *rows[0].Volume != 7 {
+		t.Fatalf("unexpected first row %v", rows[0])
+	}
+
+	if rows[1].Name != "a" || rows[1].Price != 2 || *rows[1].Volume != 3 || rows[1].Count != 4 {
+		t.Fatalf("unexpected second row %v", rows[1])
+	}
+
+	type invalid struct {
+		Price float64 `default:"x"`
+	}
+
+	var invalids []invalid
+
+	err = ReadRowsFromReader(strings.NewReader("\"\"\n"), false, &invalids)
+	if err == nil {
+		t.Fatal("expected error for invalid default")
+	}
+}
+
+func TestRows(t *testing.T) {
+	count := 0
+
+	for price, err := range Rows[dailyPrice](strings.NewReader(testData(t)), true) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if price.Date.IsZero() {
+			t.Fatalf("price %d has no date", count)
+		}
+
+		count++
+	}
+
+	if count != 10 {
+		t.Fatalf("count must be 10 but is %d", count)
+	}
+}
+
+func TestRowsError(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var names []string
+
+	for row, err := range Rows[row](strings.NewReader("a,1\nb,x\nc,3\n"), false) {
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || parseErr.Row != 2 {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			break
+		}
+
+		names = append(names, row.Name)
+	}
+
+	if len(names) != 1 || names[0] != "a" {
+		t.Fatalf("unexpected names %v", names)
+	}
+}
+
+func TestReadRowsFromReaderUnsupportedType(t *testing.T) {
+	type row struct {
+		Name    string
+		Channel chan int
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader(""), false, &rows)
+	if err == nil || err.Error() != "unsupported type chan int for field Channel" {
+		t.Fatalf("expected unsupported type error but got %v", err)
+	}
+}
+
+// Cell with only a marshaler.
+type writeOnly struct {
+	value string
+}
+
+func (w writeOnly) MarshalCSV() (string, error) {
+	return w.value, nil
+}
+
+func TestReadRowsFromReaderMarshalerOnlyType(t *testing.T) {
+	type row struct {
+		Name  string
+		Value writeOnly
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader(""), false, &rows)
+	if err == nil || err.Error() != "unsupported type csv2.writeOnly for field Value" {
+		t.Fatalf("expected unsupported type error but got %v", err)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, false, []row{{Name: "a", Value: writeOnly{"b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buffer.String() != "a,b\n" {
+		t.Fatalf("actual %q must be %q", buffer.String(), "a,b\n")
+	}
+}
+
+// Embedded unexported struct.
+type audit struct {
+	Author string
+}
+
+func TestReadRowsFromReaderUnexportedFields(t *testing.T) {
+	type row struct {
+		audit
+		Name   string
+		secret string
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("name,secret,author\na,x,b\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Name != "a" || rows[0].Author != "b" || rows[0].secret != "" {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, true, []row{{audit: audit{"b"}, Name: "a", secret: "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "Author,Name\nb,a\n"; buffer.String() != expected {
+		t.Fatalf("actual %q must be %q", buffer.String(), expected)
+	}
+}
+
+func TestReadTableFromReaderUnsupportedType(t *testing.T) {
+	table := struct {
+		Name     []string
+		Channels []chan int
+	}{}
+
+	err := ReadTableFromReader(strings.NewReader(""), false, &table)
+	if err == nil || err.Error() != "unsupported type chan int for field Channels" {
+		t.Fatalf("expected unsupported type error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderPointerRows(t *testing.T) {
+	var prices []*dailyPrice
+
+	err := ReadRowsFromReader(strings.NewReader(testData(t)), true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 10 {
+		t.Fatalf("prices must have 10 element but has %d", n)
+	}
+
+	if prices[0] == prices[1] || prices[0].Close != 43.48 || prices[1].Close != 44.11 {
+		t.Fatalf("unexpected prices %v %v", prices[0], prices[1])
+	}
+}
+
+func TestReadRowsFromReaderNonEmptyBool(t *testing.T) {
+	type row struct {
+		Name    string
+		Checked bool `format:"nonempty"`
+		Active  bool
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("name,checked,active\na,x,true\nb,,false\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !rows[0].Checked || !rows[0].Active || rows[1].Checked || rows[1].Active {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadRowsFromReaderBigNumbers(t *testing.T) {
+	type row struct {
+		Amount *big.Int
+		Rate   *big.Float
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("amount,rate\n123456789012345678901234567890,0.125\n,\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := rows[0].Amount.String(); actual != "123456789012345678901234567890" {
+		t.Fatalf("actual %q must be 123456789012345678901234567890", actual)
+	}
+
+	if actual := rows[0].Rate.Text('f', -1); actual != "0.125" {
+		t.Fatalf("actual %q must be 0.125", actual)
+	}
+
+	if rows[1].Amount != nil || rows[1].Rate != nil {
+		t.Fatalf("unexpected row %v", rows[1])
+	}
+
+	err = ReadRowsFromReader(strings.NewReader("amount,rate\n12x,1\n"), true, &rows)
+	if err == nil {
+		t.Fatal("expected error for invalid big integer")
+	}
+}
+
+func TestReadRowsFromReaderJSON(t *testing.T) {
+	type tags struct {
+		Sector string   `json:"sector"`
+		Labels []string `json:"labels"`
+	}
+
+	type row struct {
+		Name string
+		Raw  json.RawMessage
+		Tags *tags `format:"json"`
+	}
+
+	var rows []row
+
+	input := "name,raw,tags\na,\"{\"\"x\"\":1}\",\"{\"\"sector\"\":\"\"tech\"\",\"\"labels\"\":[\"\"a\"\",\"\"b\"\"]}\"\nb,,\n"
+
+	err := ReadRowsFromReader(strings.NewReader(input), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(rows[0].Raw) != `{"x":1}` || rows[0].Tags.Sector != "tech" || len(rows[0].Tags.Labels) != 2 {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+
+	if rows[1].Raw != nil || rows[1].Tags != nil {
+		t.Fatalf("unexpected row %v", rows[1])
+	}
+
+	err = ReadRowsFromReader(strings.NewReader("name,raw,tags\na,{,\n"), true, &rows)
+	if err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
+
+func TestReadRowsFromReaderTimeLayouts(t *testing.T) {
+	type row struct {
+		Date time.Time `format:"2006-01-02|01/02/2006"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("date\n2021-01-02\n01/03/2021\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Date != time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC) || rows[1].Date != time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC) {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	err = ReadRowsFromReader(strings.NewReader("date\n2021.01.02\n"), true, &rows)
+	if err == nil || !strings.Contains(err.Error(), "any of the layouts 2006-01-02, 01/02/2006") {
+		t.Fatalf("expected layouts error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderCSVTag(t *testing.T) {
+	type row struct {
+		Date     time.Time `csv:"day,format=2006-01-02"`
+		AdjClose float64   `csv:"Adj Close,default=1.5" header:"close"`
+		Volume   int       `csv:",index=2" format:"base=16"`
+		Skipped  string    `csv:"-"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("Adj Close,volume,day,skipped\n,ff,2021-01-02,x\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := row{Date: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), AdjClose: 1.5, Volume: 255}
+	if rows[0] != expected {
+		t.Fatalf("actual %v must be %v", rows[0], expected)
+	}
+
+	type indexRow struct {
+		Name   string `csv:",index=1"`
+		Volume int    `csv:",index=0,format=base=16"`
+	}
+
+	var indexRows []indexRow
+
+	err = ReadRowsFromReader(strings.NewReader("ff,a\n"), false, &indexRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if indexRows[0].Name != "a" || indexRows[0].Volume != 255 {
+		t.Fatalf("unexpected row %v", indexRows[0])
+	}
+}
+
+func TestResolveColumns(t *testing.T) {
+	type row struct {
+		Name    string `header:"name"`
+		Volume  int    `header:"volume"`
+		Missing string `header:"missing"`
+	}
+
+	reader := strings.NewReader("volume,name\n1,a\n")
+
+	columns, err := ResolveColumns(reader, &row{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(columns) != 3 || columns[0].ColumnIndex != 1 || columns[1].ColumnIndex != 0 || columns[2].ColumnIndex != -1 {
+		t.Fatalf("unexpected columns %v", columns)
+	}
+
+	if !reflect.DeepEqual(columns[1].FieldIndex, []int{1}) {
+		t.Fatalf("unexpected field index %v", columns[1].FieldIndex)
+	}
+
+	_, err = ResolveColumns(reader, row{})
+	if !errors.Is(err, ErrNotPointer) {
+		t.Fatalf("expected not a pointer error but got %v", err)
+	}
+}
+
+func TestCheckHeaders(t *testing.T) {
+	type row struct {
+		Name    string `header:"name"`
+		Volume  int    `header:"volume"`
+		Missing string `header:"missing"`
+	}
+
+	err := CheckHeaders(strings.NewReader("volume,missing,name\n1,a,b\n"), &row{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = CheckHeaders(strings.NewReader("volume,name,extra\n1,a,b\n"), &row{})
+	if err == nil || err.Error() != "missing columns: missing; unexpected columns: extra" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestReadRowsFromReaderMissingColumn(t *testing.T) {
+	type row struct {
+		Missing int `header:"missing"`
+		Name    string
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("name,volume\na,x\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Missing != 0 || rows[0].Name != "a" {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderPercent(t *testing.T) {
+	type row struct {
+		Rate    float64 `format:"percent"`
+		Literal float32 `format:"percent-literal"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("rate,literal\n12.5%,12.5%\n7,-3 %\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Rate != 0.125 || rows[0].Literal != 12.5 || rows[1].Rate != 0.07 || rows[1].Literal != -3 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadRowsFromReaderBytes(t *testing.T) {
+	type row struct {
+		Token   []byte
+		Payload []byte `format:"base64"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("token,payload\nabc,aGVsbG8=\n,\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(rows[0].Token) != "abc" || string(rows[0].Payload) != "hello" || rows[1].Token != nil || rows[1].Payload != nil {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	err = ReadRowsFromReader(strings.NewReader("token,payload\nabc,!!\n"), true, &rows)
+	if err == nil {
+		t.Fatal("expected error for invalid base64")
+	}
+}
+
+func TestCountRows(t *testing.T) {
+	n, err := CountRows(strings.NewReader(testData(t)), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 10 {
+		t.Fatalf("actual %d must be 10", n)
+	}
+
+	n, err = CountRowsWithOptions(strings.NewReader("# note\na\nb\nc\nd\n"), false, Options{Comment: '#', Skip: 1, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Fatalf("actual %d must be 2", n)
+	}
+}
+
+func TestReadRowsFromReaderEnumTag(t *testing.T) {
+	type row struct {
+		Name   string
+		Status string `enum:"active|inactive|pending"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("name,status\na,active\nb,pending\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Status != "active" || rows[1].Status != "pending" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	err = ReadRowsFromReader(strings.NewReader("name,status\na,deleted\n"), true, &rows)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Header != "Status" ||
+		!strings.Contains(err.Error(), `invalid value "deleted", allowed values are active, inactive, pending`) {
+		t.Fatalf("expected enum error but got %v", err)
+	}
+
+	type optionalRow struct {
+		Name   string
+		Status *string `enum:"a|b"`
+	}
+
+	var optionalRows []optionalRow
+
+	err = ReadRowsFromReader(strings.NewReader("name,status\na,\nb,b\n"), true, &optionalRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if optionalRows[0].Status != nil || optionalRows[1].Status == nil || *optionalRows[1].Status != "b" {
+		t.Fatalf("unexpected rows %v", optionalRows)
+	}
+}
+
+func TestReadRowsFromReaderTrimTag(t *testing.T) {
+	type row struct {
+		Code  int     `trim:"*"`
+		Price float64 `trim:"$ "`
+		Name  string
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("code,price,name\n*123*,$ 4.5,*a*\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Code != 123 || rows[0].Price != 4.5 || rows[0].Name != "*a*" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadRowsFromReaderTransformTag(t *testing.T) {
+	type row struct {
+		Symbol   string `transform:"upper"`
+		Category string `transform:"upper|lower" enum:"tech|energy"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("symbol,category\naapl,Tech\nxom,ENERGY\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []row{{"AAPL", "tech"}, {"XOM", "energy"}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("actual %v must be %v", rows, expected)
+	}
+
+	var badRows []struct {
+		Name string `transform:"title"`
+	}
+
+	err = ReadRowsFromReader(strings.NewReader("name\na\n"), true, &badRows)
+	if err == nil || err.Error() != `invalid transform "title" for field Name` {
+		t.Fatalf("expected invalid transform error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderRequiredTag(t *testing.T) {
+	type row struct {
+		Date   string `required:"true"`
+		Volume int    `required:"true" default:"0"`
+		Note   string
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("date,volume,note\n2021-01-01,,\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ReadRowsFromReader(strings.NewReader("date,volume,note\n2021-01-01,1,a\n,2,b\n"), true, &rows)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Row != 2 || parseErr.Header != "Date" ||
+		!strings.Contains(err.Error(), "value is required") {
+		t.Fatalf("expected required error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderNamedTypes(t *testing.T) {
+	type symbol string
+	type volume int64
+	type day time.Time
+
+	type row struct {
+		Symbol symbol
+		Volume volume
+		Day    day  `format:"2006-01-02"`
+		Next   *day `format:"2006-01-02"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("symbol,volume,day,next\nAAPL,100,2021-01-02,2021-01-03\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Symbol != "AAPL" || rows[0].Volume != 100 ||
+		time.Time(rows[0].Day) != time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC) ||
+		time.Time(*rows[0].Next) != time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC) {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, false, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "AAPL,100,2021-01-02,2021-01-03\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestReadRowsFromReaderSQLNullTypes(t *testing.T) {
+	type row struct {
+		Name   sql.NullString
+		Volume sql.NullInt64
+		Price  sql.NullFloat64
+		Date   sql.NullTime `format:"2006-01-02"`
+		Count  sql.Null[int]
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("name,volume,price,date,count\na,1,2.5,2021-01-02,3\n,,,,\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := row{
+		Name:   sql.NullString{String: "a", Valid: true},
+		Volume: sql.NullInt64{Int64: 1, Valid: true},
+		Price:  sql.NullFloat64{Float64: 2.5, Valid: true},
+		Date:   sql.NullTime{Time: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), Valid: true},
+		Count:  sql.Null[int]{V: 3, Valid: true},
+	}
+
+	if rows[0] != expected || rows[1] != (row{}) {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadHeaders(t *testing.T) {
+	headers, err := ReadHeaders(strings.NewReader(testData(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(headers) != 13 || headers[0] != "date" || headers[12] != "splitFactor" {
+		t.Fatalf("unexpected headers %v", headers)
+	}
+
+	headers, err = ReadHeadersWithOptions(strings.NewReader(" a ; b \n1;2\n"), Options{Comma: ';', TrimSpace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(headers, []string{"a", "b"}) {
+		t.Fatalf("unexpected headers %v", headers)
+	}
+}
+
+func TestReadRowFromReader(t *testing.T) {
+	var price dailyPrice
+
+	err := ReadRowFromReader(strings.NewReader(testData(t)), true, &price)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if price.Close != 43.48 {
+		t.Fatalf("actual %f must be 43.48", price.Close)
+	}
+
+	err = ReadRowFromReader(strings.NewReader("date,close\n"), true, &price)
+	if err != io.EOF {
+		t.Fatalf("expected EOF but got %v", err)
+	}
+
+	err = ReadRowFromReader(strings.NewReader(""), false, price)
+	if !errors.Is(err, ErrNotPointer) {
+		t.Fatalf("expected not a pointer error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderNestedStructPrefix(t *testing.T) {
+	type quote struct {
+		Price float64
+		Size  int
+	}
+
+	type row struct {
+		Symbol string
+		Bid    quote     `header:"Bid."`
+		Ask    quote     `header:"Ask."`
+		Time   time.Time `header:"Time." format:"2006-01-02"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("symbol,ask.price,ask.size,bid.price,bid.size,time.\nAAPL,1.5,10,1.25,20,2021-01-02\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := row{
+		Symbol: "AAPL",
+		Bid:    quote{Price: 1.25, Size: 20},
+		Ask:    quote{Price: 1.5, Size: 10},
+		Time:   time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	if rows[0] != expected {
+		t.Fatalf("actual %v must be %v", rows[0], expected)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, true, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCSV := "Symbol,Bid.Price,Bid.Size,Ask.Price,Ask.Size,Time.\nAAPL,1.25,20,1.5,10,2021-01-02\n"
+	if actual := buffer.String(); actual != expectedCSV {
+		t.Fatalf("actual %q must be %q", actual, expectedCSV)
+	}
+}
+
+func TestReadTableFromReaderSkipField(t *testing.T) {
+	type table struct {
+		Close  []float64
+		Open   []float64
+		Change []float64 `header:"-"`
+		Source string    `header:"-"`
+	}
+
+	prices := table{Change: []float64{9}, Source: "test"}
+
+	err := ReadTableFromReader(strings.NewReader(testData(t)), true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(prices.Close) != 10 || len(prices.Open) != 10 {
+		t.Fatalf("close and open must have 10 elements but have %d and %d", len(prices.Close), len(prices.Open))
+	}
+
+	if len(prices.Change) != 1 || prices.Change[0] != 9 || prices.Source != "test" {
+		t.Fatalf("skipped fields must be untouched but are %v and %q", prices.Change, prices.Source)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteTableToWriter(&buffer, true, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header, _, _ := strings.Cut(buffer.String(), "\n"); header != "Close,Open" {
+		t.Fatalf("actual %q must be Close,Open", header)
+	}
+}
+
+func TestReadRowsMultilineFields(t *testing.T) {
+	type row struct {
+		Name   string
+		Notes  string
+		Volume int
+	}
+
+	input := "name,notes,volume\na,\"line 1\nline 2\",1\nb,\"x\r\n\"\"y\"\"\n\",2\nc,,3\nd,\"bad\nvalue\",x\n"
+
+	var notes []string
+
+	err := ForEachRow(strings.NewReader(input), true, &row{}, func(r interface{}) error {
+		notes = append(notes, r.(*row).Notes)
+		return nil
+	})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Row != 4 || parseErr.Line != 9 {
+		t.Fatalf("expected parse error on row 4 and line 9 but got %v", err)
+	}
+
+	expected := []string{"line 1\nline 2", "x\n\"y\"\n", ""}
+	if !reflect.DeepEqual(notes, expected) {
+		t.Fatalf("actual %q must be %q", notes, expected)
+	}
+
+	notes = nil
+
+	for r, err := range Rows[row](strings.NewReader(input), true) {
+		if err != nil {
+			break
+		}
+
+		notes = append(notes, r.Notes)
+	}
+
+	if !reflect.DeepEqual(notes, expected) {
+		t.Fatalf("actual %q must be %q", notes, expected)
+	}
+
+	var rows []row
+
+	err = ReadRowsFromReaderWith(strings.NewReader(input), true, &rows, WithSkip(1), WithLimit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Name != "b" || rows[1].Name != "c" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadRowsFromReaderSharedHeader(t *testing.T) {
+	type row struct {
+		Date      time.Time `header:"DateTime" format:"2006-01-02 15:04"`
+		TimeOfDay string    `header:"DateTime"`
+		Volume    int
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("volume,datetime\n1,2021-01-02 03:04\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := time.Date(2021, 1, 2, 3, 4, 0, 0, time.UTC); !rows[0].Date.Equal(expected) {
+		t.Fatalf("date %v must be %v", rows[0].Date, expected)
+	}
+
+	if rows[0].TimeOfDay != "2021-01-02 03:04" || rows[0].Volume != 1 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadRowsFromReaderAnonymousStruct(t *testing.T) {
+	var rows []struct {
+		Name string `header:"name"`
+		Age  int
+	}
+
+	err := ReadRowsFromReader(strings.NewReader("age,name\n30,a\n40,b\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Name != "a" || rows[0].Age != 30 || rows[1].Name != "b" || rows[1].Age != 40 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, true, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "name,Age\na,30\nb,40\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
@@ -0,0 +1,83 @@
+package csv2
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"slices"
+)
+
+// Decoder reads rows from a CSV stream. The column mapping of a row type is
+// computed once and reused by the following reads of the same type, also
+// after the decoder is reset to read another stream.
+type Decoder struct {
+	// Options for reading. They must be set before the first read.
+	Options
+
+	// HasHeader indicates that the first record is the header. It defaults
+	// to true.
+	HasHeader bool
+
+	reader       io.Reader
+	recordReader *recordReader
+	rowType      reflect.Type
+	columns      []Column
+}
+
+// NewDecoder returns a new decoder that reads from reader.
+func NewDecoder(reader io.Reader) *Decoder {
+	return &Decoder{
+		HasHeader: true,
+		reader:    reader,
+	}
+}
+
+// Reset makes the decoder read from reader, keeping the options and the
+// columns of the last row type. The header of reader is read on the next
+// read.
+func (d *Decoder) Reset(reader io.Reader) {
+	d.reader = reader
+	d.recordReader = nil
+}
+
+// Decode reads the next rows and appends them to the slice that rows points
+// to. It reads up to Limit rows if it is set, so that repeated calls read the
+// rows in batches, or all remaining rows otherwise. It returns io.EOF if there
+// are no more rows.
+func (d *Decoder) Decode(rows interface{}) error {
+	rowType, err := getRowType(rows)
+	if err != nil {
+		return err
+	}
+
+	if rowType != d.rowType {
+		d.columns, err = getStructFieldsAsColumns(rowType, unmarshalerType)
+		if err != nil {
+			return err
+		}
+
+		if d.recordReader != nil {
+			if err := d.recordReader.setColumns(slices.Clone(d.columns)); err != nil {
+				return err
+			}
+		}
+
+		d.rowType = rowType
+	}
+
+	if d.recordReader == nil {
+		d.recordReader, err = newRecordReader(context.Background(), d.reader, d.HasHeader, slices.Clone(d.columns), &d.Options)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.recordReader.rowCount = 0
+
+	n, err := d.recordReader.readRows(reflect.ValueOf(rows).Elem())
+	if err == nil && n == 0 {
+		return io.EOF
+	}
+
+	return err
+}
@@ -0,0 +1,133 @@
+package csv2
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoderDecode(t *testing.T) {
+	file, err := os.Open(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	var prices []dailyPrice
+
+	err = NewDecoder(file).Decode(&prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 10 {
+		t.Fatalf("prices must have 10 element but has %d", n)
+	}
+}
+
+func TestDecoderDecodeWithOptions(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	decoder := NewDecoder(strings.NewReader("volume;name\n1;a\n2;b\n"))
+	decoder.Comma = ';'
+
+	var rows []row
+
+	err := decoder.Decode(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Name != "a" || rows[1].Volume != 2 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestDecoderDecodeWithoutHeader(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	decoder := NewDecoder(strings.NewReader("a,1\nb,2\n"))
+	decoder.HasHeader = false
+
+	var rows []row
+
+	err := decoder.Decode(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Name != "a" || rows[1].Volume != 2 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestDecoderDecodeBatches(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	decoder := NewDecoder(strings.NewReader("name,volume\na,1\nb,2\nc,3\n"))
+	decoder.Limit = 2
+
+	var batches [][]row
+
+	for {
+		var rows []row
+
+		err := decoder.Decode(&rows)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		batches = append(batches, rows)
+	}
+
+	expected := [][]row{{{"a", 1}, {"b", 2}}, {{"c", 3}}}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Fatalf("actual %v must be %v", batches, expected)
+	}
+}
+
+func TestDecoderReset(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	decoder := NewDecoder(strings.NewReader("name,volume\na,1\n"))
+
+	var rows []row
+
+	if err := decoder.Decode(&rows); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := decoder.Decode(&rows); err != io.EOF {
+		t.Fatalf("expected io.EOF but got %v", err)
+	}
+
+	decoder.Reset(strings.NewReader("volume,name\n2,b\n3,c\n"))
+
+	if err := decoder.Decode(&rows); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []row{{"a", 1}, {"b", 2}, {"c", 3}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("actual %v must be %v", rows, expected)
+	}
+}
@@ -0,0 +1,113 @@
+package csv2
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Encoder writes rows to a CSV stream one at a time. The header is written
+// with the first row using the tags of its struct type.
+type Encoder struct {
+	// Options for writing. They must be set before the first row.
+	Options
+
+	// WriteHeader indicates that the header is written before the first
+	// row. It defaults to true.
+	WriteHeader bool
+
+	writer    io.Writer
+	csvWriter recordWriter
+	rowType   reflect.Type
+	columns   []Column
+	computed  []computedColumn
+	record    []string
+}
+
+// NewEncoder returns a new encoder that writes to writer.
+func NewEncoder(writer io.Writer) *Encoder {
+	return &Encoder{
+		WriteHeader: true,
+		writer:      writer,
+	}
+}
+
+// EncodeRow writes the given row, which is a struct or a pointer to struct.
+// All rows must be of the same type as the first one.
+func (e *Encoder) EncodeRow(row interface{}) error {
+	rowValue := reflect.ValueOf(row)
+	if rowValue.Kind() == reflect.Ptr {
+		if rowValue.IsNil() {
+			return errors.New("row is nil")
+		}
+
+		rowValue = rowValue.Elem()
+	}
+
+	if rowValue.Kind() != reflect.Struct {
+		return fmt.Errorf("row %w", ErrNotStruct)
+	}
+
+	if err := e.setRowType(rowValue.Type()); err != nil {
+		return err
+	}
+
+	// Copy the rows passed by value so that their fields are addressable for
+	// the pointer receiver marshalers.
+	if !rowValue.CanAddr() {
+		addressable := reflect.New(rowValue.Type()).Elem()
+		addressable.Set(rowValue)
+		rowValue = addressable
+	}
+
+	return writeRow(e.csvWriter, rowValue, e.columns, e.computed, e.record, &e.Options)
+}
+
+// setRowType initializes the encoder and writes the header for the given row
+// type on the first call, and checks that it matches on the following calls.
+func (e *Encoder) setRowType(rowType reflect.Type) error {
+	if e.csvWriter != nil {
+		if rowType != e.rowType {
+			return fmt.Errorf("row type %s does not match %s", rowType, e.rowType)
+		}
+
+		return nil
+	}
+
+	columns, err := getStructFieldsAsColumns(rowType, marshalerType)
+	if err != nil {
+		return err
+	}
+
+	columns, err = e.orderColumns(columns)
+	if err != nil {
+		return err
+	}
+
+	if err := checkColumnIndices(columns); err != nil {
+		return err
+	}
+
+	e.csvWriter = newCsvWriter(e.writer, e.Options)
+	e.rowType = rowType
+	e.columns = columns
+	e.record = make([]string, recordLength(columns)+len(e.computed))
+
+	if e.WriteHeader {
+		return writeHeaderRecord(e.csvWriter, columns, e.computed)
+	}
+
+	return nil
+}
+
+// Flush writes any buffered rows to the underlying writer.
+func (e *Encoder) Flush() error {
+	if e.csvWriter == nil {
+		return nil
+	}
+
+	e.csvWriter.Flush()
+
+	return e.csvWriter.Error()
+}
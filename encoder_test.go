@@ -0,0 +1,115 @@
+package csv2
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoderEncodeRow(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := NewEncoder(&buffer)
+	encoder.Comma = ';'
+
+	for _, r := range []row{{Name: "a", Volume: 1}, {Name: "b", Volume: 2}} {
+		if err := encoder.EncodeRow(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := encoder.EncodeRow(&row{Name: "c", Volume: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := encoder.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "name;volume\na;1\nb;2\nc;3\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestEncoderEncodeRowWithoutHeader(t *testing.T) {
+	type row struct {
+		Name string
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := NewEncoder(&buffer)
+	encoder.WriteHeader = false
+
+	if err := encoder.EncodeRow(row{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := encoder.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "a\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestEncoderEncodeRowDifferentType(t *testing.T) {
+	type row struct {
+		Name string
+	}
+
+	type other struct {
+		Volume int
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := NewEncoder(&buffer)
+
+	if err := encoder.EncodeRow(row{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := encoder.EncodeRow(other{Volume: 1}); err == nil {
+		t.Fatal("expected error for different row type")
+	}
+
+	if err := encoder.EncodeRow(1); err == nil {
+		t.Fatal("expected error for non-struct row")
+	}
+}
+
+func TestEncoderEncodeRowPointerMarshaler(t *testing.T) {
+	type row struct {
+		Location point
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := NewEncoder(&buffer)
+	encoder.WriteHeader = false
+
+	if err := encoder.EncodeRow(row{Location: point{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := encoder.EncodeRow(&row{Location: point{3, 4}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := encoder.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1:2\n3:4\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
@@ -0,0 +1,92 @@
+package csv2
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var (
+	// ErrNotPointer is returned when the rows, the table, or the row template
+	// is not a pointer.
+	ErrNotPointer = errors.New("not a pointer")
+
+	// ErrNotSlice is returned when the rows are not a slice.
+	ErrNotSlice = errors.New("not a slice")
+
+	// ErrNotStruct is returned when the row or the table is not a struct.
+	ErrNotStruct = errors.New("not a struct")
+
+	// ErrFieldsNotSlices is returned when the table fields are not all slices.
+	ErrFieldsNotSlices = errors.New("table fields must be all slices")
+)
+
+// ParseError is returned when a cell can't be parsed into its field.
+type ParseError struct {
+	// Row is the 1-based number of the data record.
+	Row int
+
+	// Column is the 0-based index of the column in the record.
+	Column int
+
+	// Header is the header of the column.
+	Header string
+
+	// Value is the string value of the cell.
+	Value string
+
+	// Line is the 1-based line of the field in the input, or the line of
+	// the last field if the record is too short. It is zero if unknown.
+	Line int
+
+	// LineColumn is the 1-based column of the field in its line, counted
+	// in bytes.
+	LineColumn int
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	position := fmt.Sprintf("row %d, column %q (index %d)", e.Row, e.Header, e.Column)
+	if e.Line > 0 {
+		position = fmt.Sprintf("line %d:%d, %s", e.Line, e.LineColumn, position)
+	}
+
+	if numErr, ok := e.Err.(*strconv.NumError); ok {
+		return fmt.Sprintf("%s: parsing %q: %v", position, e.Value, numErr.Err)
+	}
+
+	return fmt.Sprintf("%s: %v", position, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// rangeError is returned when a number is out of the range of its field type.
+type rangeError struct {
+	field     string
+	fieldType reflect.Type
+	value     string
+	err       error
+}
+
+func (e *rangeError) Error() string {
+	return fmt.Sprintf("field %q (%s): value %s out of range", e.field, e.fieldType, e.value)
+}
+
+func (e *rangeError) Unwrap() error {
+	return e.err
+}
+
+func newParseError(row int, column Column, value string, err error) *ParseError {
+	return &ParseError{
+		Row:    row,
+		Column: column.ColumnIndex,
+		Header: column.Header,
+		Value:  value,
+		Err:    err,
+	}
+}
@@ -0,0 +1,152 @@
+package csv2
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int64
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("Name,Volume\na,1\nb,abc\n"), true, &rows)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected parse error but got %v", err)
+	}
+
+	if parseErr.Row != 2 || parseErr.Column != 1 || parseErr.Header != "Volume" || parseErr.Value != "abc" {
+		t.Fatalf("unexpected parse error %+v", parseErr)
+	}
+
+	expected := `line 3:3, row 2, column "Volume" (index 1): parsing "abc": invalid syntax`
+	if actual := err.Error(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestParseErrorTable(t *testing.T) {
+	table := struct {
+		Name   []string
+		Volume []int64
+	}{}
+
+	err := ReadTableFromReader(strings.NewReader("a,1\nb,abc\n"), false, &table)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected parse error but got %v", err)
+	}
+
+	if parseErr.Row != 2 || parseErr.Column != 1 {
+		t.Fatalf("unexpected parse error %+v", parseErr)
+	}
+}
+
+func TestParseErrorShortRecord(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int64
+		Date   string
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("a,1\n"), false, &rows)
+
+	expected := `line 1:3, row 1, column "Date" (index 2): record has 2 fields but column index 2 requested`
+	if err == nil || err.Error() != expected {
+		t.Fatalf("actual %v must be %q", err, expected)
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	var prices []dailyPrice
+	var names []string
+	var table stockPrices
+	var badTable struct {
+		Close float64
+	}
+
+	reader := strings.NewReader(testData(t))
+
+	tests := []struct {
+		err      error
+		expected error
+	}{
+		{ReadRowsFromReader(reader, true, prices), ErrNotPointer},
+		{ReadRowsFromReader(reader, true, &table), ErrNotSlice},
+		{ReadRowsFromReader(reader, true, &names), ErrNotStruct},
+		{ForEachRow(reader, true, nil, nil), ErrNotPointer},
+		{ReadTableFromReader(reader, true, table), ErrNotPointer},
+		{ReadTableFromReader(reader, true, &prices), ErrNotStruct},
+		{ReadTableFromReader(reader, true, &badTable), ErrFieldsNotSlices},
+		{WriteRowsToWriter(io.Discard, true, table), ErrNotSlice},
+		{WriteRowsToWriter(io.Discard, true, names), ErrNotStruct},
+		{WriteTableToWriter(io.Discard, true, prices), ErrNotStruct},
+	}
+
+	for i, test := range tests {
+		if !errors.Is(test.err, test.expected) {
+			t.Fatalf("test %d: expected %v but got %v", i, test.expected, test.err)
+		}
+	}
+}
+
+func TestParseErrorOutOfRange(t *testing.T) {
+	type row struct {
+		Count  int16   `header:"count"`
+		Volume *uint8  `header:"volume"`
+		Price  float32 `header:"price"`
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"count\n70000\n", `line 2:1, row 1, column "count" (index 0): field "Count" (int16): value 70000 out of range`},
+		{"volume\n256\n", `line 2:1, row 1, column "volume" (index 0): field "Volume" (uint8): value 256 out of range`},
+		{"price\n1e39\n", `line 2:1, row 1, column "price" (index 0): field "Price" (float32): value 1e39 out of range`},
+	}
+
+	for _, test := range tests {
+		var rows []row
+
+		err := ReadRowsFromReader(strings.NewReader(test.input), true, &rows)
+		if !errors.Is(err, strconv.ErrRange) {
+			t.Fatalf("expected range error but got %v", err)
+		}
+
+		if actual := err.Error(); actual != test.expected {
+			t.Fatalf("actual %q must be %q", actual, test.expected)
+		}
+	}
+}
+
+func TestParseErrorLine(t *testing.T) {
+	type row struct {
+		Note   string
+		Volume int
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("note,volume\n\"a\nb\",1\n\"c\nd\ne\",  x\n"), true, &rows)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected parse error but got %v", err)
+	}
+
+	if parseErr.Row != 2 || parseErr.Line != 6 || parseErr.LineColumn != 4 {
+		t.Fatalf("unexpected parse error %+v", parseErr)
+	}
+}
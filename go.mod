@@ -1,3 +1,5 @@
 module github.com/cinar/csv2
 
-go 1.16
+go 1.23
+
+require golang.org/x/text v0.14.0
@@ -0,0 +1,146 @@
+package csv2
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// InferredColumn is a column of a CSV stream with its type inferred from the
+// sample rows.
+type InferredColumn struct {
+	// Header is the column header.
+	Header string
+
+	// Type is the inferred type of the column, one of int64, float64, bool,
+	// time.Time, or string.
+	Type reflect.Type
+
+	// Format is the time layout of the time.Time columns.
+	Format string
+}
+
+// Candidate time layouts tried in order.
+var inferredTimeLayouts = [...]string{timeFormat, time.RFC3339, "2006-01-02"}
+
+// inferredType tracks the types that all the non-empty values of a column
+// can be parsed as.
+type inferredType struct {
+	empty    bool
+	notInt   bool
+	notFloat bool
+	notBool  bool
+	notTime  [len(inferredTimeLayouts)]bool
+}
+
+func (t *inferredType) add(stringValue string) {
+	if stringValue == "" {
+		return
+	}
+
+	t.empty = false
+
+	if !t.notInt {
+		_, err := strconv.ParseInt(stringValue, 10, 64)
+		t.notInt = err != nil
+	}
+
+	if !t.notFloat {
+		_, err := strconv.ParseFloat(stringValue, 64)
+		t.notFloat = err != nil
+	}
+
+	if !t.notBool {
+		_, err := strconv.ParseBool(stringValue)
+		t.notBool = err != nil
+	}
+
+	for i, layout := range inferredTimeLayouts {
+		if !t.notTime[i] {
+			_, err := time.Parse(layout, stringValue)
+			t.notTime[i] = err != nil
+		}
+	}
+}
+
+// timeLayout returns the index of the first time layout that parsed all
+// values, or -1 if none did.
+func (t *inferredType) timeLayout() int {
+	for i, notTime := range t.notTime {
+		if !notTime {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (t *inferredType) column(header string) InferredColumn {
+	column := InferredColumn{Header: header, Type: reflect.TypeOf("")}
+
+	if t.empty {
+		return column
+	}
+
+	switch {
+	case !t.notInt:
+		column.Type = reflect.TypeOf(int64(0))
+
+	case !t.notFloat:
+		column.Type = reflect.TypeOf(float64(0))
+
+	case !t.notBool:
+		column.Type = reflect.TypeOf(false)
+
+	case t.timeLayout() != -1:
+		column.Type = reflect.TypeOf(time.Time{})
+		column.Format = inferredTimeLayouts[t.timeLayout()]
+	}
+
+	return column
+}
+
+// Infer the columns of reader from its header and up to the given number of
+// sample rows, or all rows if it is not positive. The type of each column is
+// the first of int64, float64, bool, and time.Time that all its non-empty
+// values can be parsed as, or string otherwise.
+func InferSchema(reader io.Reader, sampleRows int) ([]InferredColumn, error) {
+	options := Options{FieldsPerRecord: -1}
+
+	csvReader := newCsvReader(reader, options)
+
+	headers, err := readHeader(csvReader, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]inferredType, len(headers))
+	for i := range types {
+		types[i].empty = true
+	}
+
+	for n := 0; sampleRows <= 0 || n < sampleRows; n++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range types {
+			if i < len(record) {
+				types[i].add(record[i])
+			}
+		}
+	}
+
+	columns := make([]InferredColumn, len(headers))
+	for i, header := range headers {
+		columns[i] = types[i].column(header)
+	}
+
+	return columns, nil
+}
@@ -0,0 +1,65 @@
+package csv2
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInferSchema(t *testing.T) {
+	input := "id,price,active,date,name,empty,mixed\n" +
+		"1,1.5,true,2021-01-02,a,,1\n" +
+		"2,2,false,2021-01-03,b,,x\n" +
+		"3,,t,,c\n" +
+		"x,x,x,x,x,x,x\n"
+
+	columns, err := InferSchema(strings.NewReader(input), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"int64", "float64", "bool", "time.Time", "string", "string", "string"}
+
+	if len(columns) != len(expected) {
+		t.Fatalf("columns must have %d elements but has %d", len(expected), len(columns))
+	}
+
+	for i, column := range columns {
+		if column.Type.String() != expected[i] {
+			t.Fatalf("column %s type %s must be %s", column.Header, column.Type, expected[i])
+		}
+	}
+
+	if columns[3].Header != "date" || columns[3].Format != "2006-01-02" {
+		t.Fatalf("unexpected date column %v", columns[3])
+	}
+
+	columns, err = InferSchema(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, column := range columns {
+		if column.Type.String() != "string" {
+			t.Fatalf("column %s type %s must be string", column.Header, column.Type)
+		}
+	}
+
+	columns, err = InferSchema(strings.NewReader("time\n2021-01-02T03:04:05Z\n"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if columns[0].Format != time.RFC3339 {
+		t.Fatalf("actual %q must be %q", columns[0].Format, time.RFC3339)
+	}
+
+	columns, err = InferSchema(strings.NewReader("time\n2020-01-01 00:00:00\n2020-01-01T00:00:00Z\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if columns[0].Type.String() != "string" {
+		t.Fatalf("column %s type %s must be string", columns[0].Header, columns[0].Type)
+	}
+}
@@ -0,0 +1,441 @@
+package csv2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"golang.org/x/text/transform"
+)
+
+// Options for reading and writing.
+type Options struct {
+	// Comma is the field delimiter. It defaults to ','.
+	Comma rune
+
+	// Comment is the prefix of the lines to ignore. It is disabled if zero.
+	Comment rune
+
+	// Transformer decodes the input to UTF-8 before parsing, for example
+	// charmap.Windows1252.NewDecoder(). It is disabled if nil.
+	Transformer transform.Transformer
+
+	// LazyQuotes allows quotes in unquoted fields and non-doubled quotes in
+	// quoted fields.
+	LazyQuotes bool
+
+	// TrimLeadingSpace trims the leading white space of the fields, even if
+	// the field delimiter is a white space.
+	TrimLeadingSpace bool
+
+	// FieldsPerRecord is the number of expected fields per record. If it is
+	// zero, the number of fields in the first record is used. If it is
+	// negative, records may have a variable number of fields.
+	FieldsPerRecord int
+
+	// DefaultTimeFormat is the layout for the time fields without a format
+	// tag. It defaults to "2006-01-02 15:04:05".
+	DefaultTimeFormat string
+
+	// Location is the location of the times read without a zone in their
+	// layout, and of the wall clock of the Excel serial dates written. It
+	// defaults to UTC if nil.
+	Location *time.Location
+
+	// TrueValues are the additional case-insensitive values for true.
+	TrueValues []string
+
+	// FalseValues are the additional case-insensitive values for false.
+	FalseValues []string
+
+	// NullValues are the case-insensitive values read as empty cells, for
+	// example "NULL" or "\N".
+	NullValues []string
+
+	// TrimSpace trims the surrounding white space of cells and headers.
+	TrimSpace bool
+
+	// CaseSensitiveHeaders matches the headers case-sensitively.
+	CaseSensitiveHeaders bool
+
+	// RequireAllColumns requires all columns to be found in the header.
+	RequireAllColumns bool
+
+	// HeaderNormalizer is applied to both the file headers and the column
+	// headers before matching them, for example to lowercase them and strip
+	// the spaces and underscores. It is disabled if nil.
+	HeaderNormalizer func(string) string
+
+	// HeaderMatcher reports whether the header of a field matches a header
+	// in the file. It replaces the default case-insensitive match if set.
+	HeaderMatcher func(fieldHeader, fileHeader string) bool
+
+	// DecimalSeparator is the decimal separator of the float values. It
+	// defaults to '.'.
+	DecimalSeparator rune
+
+	// ThousandsSeparator is the digit grouping separator removed from the
+	// integer and float values before parsing. For the float values only, it
+	// defaults to '.' if the decimal separator is something else.
+	ThousandsSeparator rune
+
+	// Columns restricts reading to the columns with the given headers. The
+	// other fields are left with their zero values.
+	Columns []string
+
+	// IgnoreMissingColumns ignores the columns given in Columns that are
+	// not found in the header.
+	IgnoreMissingColumns bool
+
+	// PadShortRecords reads the missing trailing fields of the short records
+	// as empty cells. The records may have a variable number of fields unless
+	// FieldsPerRecord is set.
+	PadShortRecords bool
+
+	// DisallowUnknownColumns rejects the headers that are not mapped to any
+	// of the columns read.
+	DisallowUnknownColumns bool
+
+	// DisallowExtraColumns rejects the records with more fields than the
+	// highest mapped column index expects.
+	DisallowExtraColumns bool
+
+	// Order restricts writing to the columns with the given headers, in the
+	// given order.
+	Order []string
+
+	// AlwaysQuote quotes every field when writing.
+	AlwaysQuote bool
+
+	// UseCRLF uses \r\n as the line terminator when writing. It defaults
+	// to \n.
+	UseCRLF bool
+
+	// HeaderRow is the 0-based index of the header record. The records
+	// before it are discarded.
+	HeaderRow int
+
+	// SkipRepeatedHeaders skips the data records identical to the header,
+	// such as the headers of concatenated files.
+	SkipRepeatedHeaders bool
+
+	// SizeHint is the expected number of rows used to pre-grow the rows
+	// slice. It is disabled if zero.
+	SizeHint int
+
+	// Skip is the number of leading data records to discard before parsing.
+	// The row numbers of the errors still count the discarded records.
+	Skip int
+
+	// Limit is the maximum number of rows to read. The rest of the stream is
+	// not read. It is unlimited if zero.
+	Limit int
+
+	// OnParseError is called with the column, the raw cell value before any
+	// trimming or default, and the parse error of each cell that fails to
+	// parse. If it returns nil, the field is left with its zero value and
+	// reading continues. Otherwise reading stops with the returned error. It
+	// is disabled if nil.
+	OnParseError func(column Column, raw string, err error) error
+
+	// SkipErrors skips the rows that fail to parse instead of aborting.
+	SkipErrors bool
+
+	// RowErrors collects the parse errors of the skipped rows if set.
+	RowErrors *[]*ParseError
+
+	// VerifyTableLengths checks that the column slices of a table have the
+	// same length after reading.
+	VerifyTableLengths bool
+}
+
+// UTF-8 byte order mark.
+var bom = []byte{0xEF, 0xBB, 0xBF}
+
+func skipBom(reader io.Reader) io.Reader {
+	bufferedReader := bufio.NewReader(reader)
+
+	if prefix, err := bufferedReader.Peek(len(bom)); err == nil && bytes.Equal(prefix, bom) {
+		bufferedReader.Discard(len(bom))
+	}
+
+	return bufferedReader
+}
+
+func newCsvReader(reader io.Reader, options Options) *csv.Reader {
+	if options.Transformer != nil {
+		reader = transform.NewReader(reader, options.Transformer)
+	}
+
+	csvReader := csv.NewReader(skipBom(reader))
+
+	if options.Comma != 0 {
+		csvReader.Comma = options.Comma
+	}
+
+	csvReader.Comment = options.Comment
+	csvReader.LazyQuotes = options.LazyQuotes
+	csvReader.TrimLeadingSpace = options.TrimLeadingSpace
+	csvReader.FieldsPerRecord = options.FieldsPerRecord
+
+	if options.PadShortRecords && options.FieldsPerRecord == 0 {
+		csvReader.FieldsPerRecord = -1
+	}
+
+	return csvReader
+}
+
+func newCsvWriter(writer io.Writer, options Options) recordWriter {
+	comma := ','
+	if options.Comma != 0 {
+		comma = options.Comma
+	}
+
+	if options.AlwaysQuote {
+		return newQuoteWriter(writer, comma, options.UseCRLF)
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Comma = comma
+	csvWriter.UseCRLF = options.UseCRLF
+
+	return csvWriter
+}
+
+func (options *Options) timeLayout(format string) string {
+	if format != "" {
+		return format
+	}
+
+	if options.DefaultTimeFormat != "" {
+		return options.DefaultTimeFormat
+	}
+
+	return timeFormat
+}
+
+// removeThousandsSeparator removes the explicit thousands separator only, so
+// that an integer is never read from a value with a decimal part.
+func (options *Options) removeThousandsSeparator(stringValue string) string {
+	if options.ThousandsSeparator != 0 {
+		stringValue = strings.ReplaceAll(stringValue, string(options.ThousandsSeparator), "")
+	}
+
+	return stringValue
+}
+
+func (options *Options) normalizeFloat(stringValue string) string {
+	if options.ThousandsSeparator == 0 && options.DecimalSeparator != 0 && options.DecimalSeparator != '.' {
+		stringValue = strings.ReplaceAll(stringValue, ".", "")
+	} else {
+		stringValue = options.removeThousandsSeparator(stringValue)
+	}
+
+	if options.DecimalSeparator != 0 && options.DecimalSeparator != '.' {
+		stringValue = strings.ReplaceAll(stringValue, string(options.DecimalSeparator), ".")
+	}
+
+	return stringValue
+}
+
+func (options *Options) localizeFloat(stringValue string) string {
+	if options.DecimalSeparator != 0 && options.DecimalSeparator != '.' {
+		stringValue = strings.ReplaceAll(stringValue, ".", string(options.DecimalSeparator))
+	}
+
+	return stringValue
+}
+
+func (options *Options) isNullValue(stringValue string) bool {
+	for _, nullValue := range options.NullValues {
+		if strings.EqualFold(nullValue, stringValue) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (options *Options) matchHeader(columnHeader, header string) bool {
+	if options.HeaderNormalizer != nil {
+		columnHeader = options.HeaderNormalizer(columnHeader)
+		header = options.HeaderNormalizer(header)
+	}
+
+	if options.HeaderMatcher != nil {
+		return options.HeaderMatcher(columnHeader, header)
+	}
+
+	if options.CaseSensitiveHeaders {
+		return columnHeader == header
+	}
+
+	return strings.EqualFold(columnHeader, header)
+}
+
+func (options *Options) selectColumns(columns []Column) []Column {
+	if len(options.Columns) == 0 {
+		return columns
+	}
+
+	selected := make([]Column, 0, len(options.Columns))
+
+	for _, column := range columns {
+		for _, header := range options.Columns {
+			if options.matchHeader(column.Header, header) {
+				selected = append(selected, column)
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// orderColumns returns the columns in the order given by the options, with
+// their column indices set to their positions.
+func (options *Options) orderColumns(columns []Column) ([]Column, error) {
+	if len(options.Order) == 0 {
+		return columns, nil
+	}
+
+	ordered := make([]Column, 0, len(options.Order))
+
+	var missing []string
+
+	for _, header := range options.Order {
+		found := false
+
+		for _, column := range columns {
+			if options.matchHeader(column.Header, header) || (column.WriteHeader != "" && options.matchHeader(column.WriteHeader, header)) {
+				column.ColumnIndex = len(ordered)
+				ordered = append(ordered, column)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, header)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("columns not found in struct: %s", strings.Join(missing, ", "))
+	}
+
+	return ordered, nil
+}
+
+func (options *Options) checkSelectedColumns(headers []string) error {
+	if options.IgnoreMissingColumns {
+		return nil
+	}
+
+	var missing []string
+
+	for _, column := range options.Columns {
+		found := false
+
+		for _, header := range headers {
+			if options.matchHeader(column, header) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, column)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("columns not found in header: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// parseError passes the parse error with the raw cell value to the hook if
+// it is set.
+func (options *Options) parseError(value reflect.Value, column Column, raw string, parseErr *ParseError) error {
+	if options.OnParseError == nil {
+		return parseErr
+	}
+
+	if hookErr := options.OnParseError(column, raw, parseErr); hookErr != nil {
+		return hookErr
+	}
+
+	value.Set(reflect.Zero(value.Type()))
+
+	return nil
+}
+
+func (options *Options) skipError(err error) bool {
+	var parseErr *ParseError
+	if !options.SkipErrors || !errors.As(err, &parseErr) {
+		return false
+	}
+
+	if options.RowErrors != nil {
+		*options.RowErrors = append(*options.RowErrors, parseErr)
+	}
+
+	return true
+}
+
+// Option is a functional option that sets a field of the options.
+type Option func(*Options)
+
+// WithComma sets the field delimiter.
+func WithComma(comma rune) Option {
+	return func(options *Options) {
+		options.Comma = comma
+	}
+}
+
+// WithComment sets the prefix of the lines to ignore.
+func WithComment(comment rune) Option {
+	return func(options *Options) {
+		options.Comment = comment
+	}
+}
+
+// WithTrimSpace sets whether the surrounding white space of cells and headers
+// is trimmed.
+func WithTrimSpace(trimSpace bool) Option {
+	return func(options *Options) {
+		options.TrimSpace = trimSpace
+	}
+}
+
+// WithSkip sets the number of leading data records to discard.
+func WithSkip(skip int) Option {
+	return func(options *Options) {
+		options.Skip = skip
+	}
+}
+
+// WithLimit sets the maximum number of rows to read.
+func WithLimit(limit int) Option {
+	return func(options *Options) {
+		options.Limit = limit
+	}
+}
+
+func newOptions(opts []Option) Options {
+	options := Options{}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
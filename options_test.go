@@ -0,0 +1,914 @@
+package csv2
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	testTsvFile = "test.tsv"
+)
+
+func TestReadRowsFromReaderWithOptionsComma(t *testing.T) {
+	file, err := os.Open(testTsvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	var prices []dailyPrice
+
+	err = ReadRowsFromReaderWithOptions(file, true, &prices, Options{Comma: '\t'})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 10 {
+		t.Fatalf("prices must have 10 element but has %d", n)
+	}
+}
+
+func TestReadTableFromReaderWithOptionsComma(t *testing.T) {
+	file, err := os.Open(testTsvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	prices := stockPrices{}
+
+	err = ReadTableFromReaderWithOptions(file, true, &prices, Options{Comma: '\t'})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices.Date); n != 10 {
+		t.Fatalf("date must have 10 elements but has %d", n)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsBoolValues(t *testing.T) {
+	type answer struct {
+		Question string
+		Answer   bool
+	}
+
+	options := Options{
+		TrueValues:  []string{"Y", "yes"},
+		FalseValues: []string{"N", "no"},
+	}
+
+	var answers []answer
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("a,y\nb,NO\nc,true\n"), false, &answers, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !answers[0].Answer || answers[1].Answer || !answers[2].Answer {
+		t.Fatalf("unexpected answers %v", answers)
+	}
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader("a,maybe\n"), false, &answers, options)
+	if err == nil || !strings.Contains(err.Error(), "Y, yes") {
+		t.Fatalf("expected error listing accepted values but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsSkipErrors(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var rowErrors []*ParseError
+
+	options := Options{
+		SkipErrors: true,
+		RowErrors:  &rowErrors,
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("a,1\nb,x\nc,3\nd,y\n"), false, &rows, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Name != "a" || rows[1].Name != "c" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Row != 2 || rowErrors[1].Row != 4 {
+		t.Fatalf("unexpected row errors %v", rowErrors)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsSkipErrorsFieldCount(t *testing.T) {
+	type row struct {
+		A int
+		B int
+	}
+
+	input := "a,b\n1,2\n3\n4,5\n6,7,8\n"
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader(input), true, &rows)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Row != 2 || parseErr.Line != 3 || !errors.Is(err, csv.ErrFieldCount) {
+		t.Fatalf("expected field count error on row 2 but got %v", err)
+	}
+
+	var rowErrors []*ParseError
+
+	rows = nil
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{SkipErrors: true, RowErrors: &rowErrors})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(rows, []row{{1, 2}, {4, 5}}) {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Row != 2 || rowErrors[0].Column != 1 || rowErrors[0].Header != "b" ||
+		rowErrors[1].Row != 4 || rowErrors[1].Column != 2 || rowErrors[1].Value != "8" {
+		t.Fatalf("unexpected row errors %v", rowErrors)
+	}
+}
+
+func TestReadTableFromReaderWithOptionsSkipErrors(t *testing.T) {
+	table := struct {
+		Name   []string
+		Volume []int
+	}{}
+
+	err := ReadTableFromReaderWithOptions(strings.NewReader("a,1\nb,x\nc,3\n"), false, &table, Options{SkipErrors: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(table.Name) != 2 || len(table.Volume) != 2 || table.Name[1] != "c" || table.Volume[1] != 3 {
+		t.Fatalf("unexpected table %v", table)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsFieldsPerRecord(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("a,1\nb,2\n"), false, &rows, Options{FieldsPerRecord: 3})
+	if !errors.Is(err, csv.ErrFieldCount) {
+		t.Fatalf("expected field count error but got %v", err)
+	}
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader("a,1\nb\n"), false, &rows, Options{FieldsPerRecord: -1})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Row != 2 || parseErr.Column != 1 {
+		t.Fatalf("expected parse error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsTrimSpace(t *testing.T) {
+	type row struct {
+		Date   string
+		Volume int
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(" Volume , Date \n 123 , 2021-01-02\n"), true, &rows, Options{TrimSpace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Volume != 123 || rows[0].Date != "2021-01-02" {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsTrimLeadingSpace(t *testing.T) {
+	type row struct {
+		Name string
+		Note string
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("name, note\na ,  \"b \"\n"), true, &rows, Options{TrimLeadingSpace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Name != "a " || rows[0].Note != "b " {
+		t.Fatalf("unexpected row %q", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsCaseSensitiveHeaders(t *testing.T) {
+	type row struct {
+		Name  string `header:"name"`
+		Lower string `header:"id"`
+		Upper string `header:"ID"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("ID,name,id\n1,a,2\n"), true, &rows, Options{CaseSensitiveHeaders: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Name != "a" || rows[0].Lower != "2" || rows[0].Upper != "1" {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsHeaderNormalizer(t *testing.T) {
+	type row struct {
+		AdjClose  float64 `header:"adjClose"`
+		AdjOpen   float64 `header:"Adj Open"`
+		AdjVolume int     `header:"adj_volume"`
+	}
+
+	normalizer := strings.NewReplacer(" ", "", "_", "").Replace
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("Adj Close,adj_open,adjVolume\n1.5,2.5,3\n"), true, &rows, Options{
+		HeaderNormalizer: normalizer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].AdjClose != 1.5 || rows[0].AdjOpen != 2.5 || rows[0].AdjVolume != 3 {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsHeaderMatcher(t *testing.T) {
+	type row struct {
+		Close float64
+		Open  float64
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("Open (USD),Close (USD)\n1.5,2.5\n"), true, &rows, Options{
+		HeaderMatcher: func(fieldHeader, fileHeader string) bool {
+			return strings.HasPrefix(fileHeader, fieldHeader+" ")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Open != 1.5 || rows[0].Close != 2.5 {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsRequireAllColumns(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+		Price  int    `header:"prize"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("name,volume,price\na,1,2\n"), true, &rows, Options{RequireAllColumns: true})
+
+	expected := "columns not found in header: prize"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("actual %v must be %q", err, expected)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsLocation(t *testing.T) {
+	type event struct {
+		Local time.Time
+		Zoned time.Time `format:"2006-01-02T15:04:05Z07:00"`
+		Unix  time.Time `format:"unix"`
+		Excel time.Time `format:"excel"`
+	}
+
+	location := time.FixedZone("EST", -5*60*60)
+
+	var events []event
+
+	input := "2021-01-02 03:04:05,2021-01-02T03:04:05Z,1609556645,44198.5\n"
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(input), false, &events, Options{Location: location})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := time.Date(2021, 1, 2, 3, 4, 5, 0, location); !events[0].Local.Equal(expected) || events[0].Local.Location() != location {
+		t.Fatalf("local %v must be %v", events[0].Local, expected)
+	}
+
+	if expected := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC); !events[0].Zoned.Equal(expected) || !events[0].Unix.Equal(expected) {
+		t.Fatalf("zoned %v and unix %v must be %v", events[0].Zoned, events[0].Unix, expected)
+	}
+
+	if events[0].Unix.Location() != location {
+		t.Fatalf("unix %v must be in %v", events[0].Unix, location)
+	}
+
+	if expected := time.Date(2021, 1, 2, 12, 0, 0, 0, location); !events[0].Excel.Equal(expected) {
+		t.Fatalf("excel %v must be %v", events[0].Excel, expected)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsDefaultTimeFormat(t *testing.T) {
+	type event struct {
+		Start time.Time
+		End   time.Time `format:"2006-01-02"`
+	}
+
+	options := Options{
+		DefaultTimeFormat: time.RFC3339,
+	}
+
+	var events []event
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("2021-01-02T03:04:05Z,2021-01-03\n"), false, &events, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC); !events[0].Start.Equal(expected) {
+		t.Fatalf("start %v must be %v", events[0].Start, expected)
+	}
+
+	if expected := time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC); !events[0].End.Equal(expected) {
+		t.Fatalf("end %v must be %v", events[0].End, expected)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriterWithOptions(&buffer, false, events, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "2021-01-02T03:04:05Z,2021-01-03\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsComment(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	var rows []row
+
+	data := "# exported by test\nname,volume\n# provenance\na,1\nb,2\n"
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(data), true, &rows, Options{Comment: '#'})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Name != "a" || rows[1].Volume != 2 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsDecimalSeparator(t *testing.T) {
+	type row struct {
+		Name  string
+		Price float64
+	}
+
+	data := "a;1.234,56\nb;-0,5\n"
+
+	options := Options{
+		Comma:            ';',
+		DecimalSeparator: ',',
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(data), false, &rows, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Price != 1234.56 || rows[1].Price != -0.5 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriterWithOptions(&buffer, false, rows, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "a;1234,56\nb;-0,5\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsThousandsSeparator(t *testing.T) {
+	type row struct {
+		Name  string
+		Price float32
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("a,\"1,234.5\"\n"), false, &rows, Options{ThousandsSeparator: ','})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Price != 1234.5 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsThousandsSeparatorInt(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int64
+		Count  uint32
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("a,\"1,234,567\",\"9,876\"\n"), false, &rows, Options{ThousandsSeparator: ','})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Volume != 1234567 || rows[0].Count != 9876 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader("a,\"1,2x4\",1\n"), false, &rows, Options{ThousandsSeparator: ','})
+	if err == nil || !strings.Contains(err.Error(), `parsing "1,2x4": invalid syntax`) {
+		t.Fatalf("expected syntax error but got %v", err)
+	}
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader("a;1.5;1\n"), false, &rows, Options{Comma: ';', DecimalSeparator: ','})
+	if err == nil || !strings.Contains(err.Error(), `parsing "1.5": invalid syntax`) {
+		t.Fatalf("expected syntax error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsTransformer(t *testing.T) {
+	type row struct {
+		Name string
+		City string
+	}
+
+	data := "Jos\xe9,M\xfcnchen\n"
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(data), false, &rows, Options{Transformer: charmap.ISO8859_1.NewDecoder()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Name != "José" || rows[0].City != "München" {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsLazyQuotes(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	data := "a \"quoted\" name,1\n"
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader(data), false, &rows)
+	if !errors.Is(err, csv.ErrBareQuote) {
+		t.Fatalf("expected bare quote error but got %v", err)
+	}
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader(data), false, &rows, Options{LazyQuotes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Name != "a \"quoted\" name" || rows[0].Volume != 1 {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsColumns(t *testing.T) {
+	var prices []dailyPrice
+
+	options := Options{
+		Columns: []string{"date", "close"},
+	}
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(testData(t)), true, &prices, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prices[0].Close != 43.48 || prices[0].Date.IsZero() || prices[0].Open != 0 || prices[0].Volume != 0 {
+		t.Fatalf("unexpected price %v", prices[0])
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsColumnsMissing(t *testing.T) {
+	var prices []dailyPrice
+
+	options := Options{
+		Columns: []string{"date", "price"},
+	}
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(testData(t)), true, &prices, options)
+	if err == nil || err.Error() != "columns not found in header: price" {
+		t.Fatalf("expected missing column error but got %v", err)
+	}
+
+	options.IgnoreMissingColumns = true
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader(testData(t)), true, &prices, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 10 {
+		t.Fatalf("prices must have 10 element but has %d", n)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsLimit(t *testing.T) {
+	var prices []dailyPrice
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(testData(t)), true, &prices, Options{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 3 {
+		t.Fatalf("prices must have 3 elements but has %d", n)
+	}
+
+	if prices[2].Close != 43.9 {
+		t.Fatalf("unexpected close %f", prices[2].Close)
+	}
+}
+
+func TestReadTableFromReaderWithOptionsLimit(t *testing.T) {
+	var prices stockPrices
+
+	err := ReadTableFromReaderWithOptions(strings.NewReader(testData(t)), true, &prices, Options{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices.Close); n != 2 {
+		t.Fatalf("close must have 2 elements but has %d", n)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsSkip(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var rows []row
+
+	input := "name,volume\n-- generated --,\n,\na,1\nb,x\nc,3\n"
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{Skip: 2})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Row != 4 {
+		t.Fatalf("expected parse error on row 4 but got %v", err)
+	}
+
+	rows = nil
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{Skip: 2, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 || rows[0].Name != "a" || rows[0].Volume != 1 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsNullValues(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume *int
+		Price  float64 `default:"1.5"`
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("name,volume,price\nnull,\\N,NA\na,2,3\n"), true, &rows, Options{
+		NullValues: []string{"NULL", "NA", "\\N"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].Name != "" || rows[0].Volume != nil || rows[0].Price != 1.5 {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+
+	if rows[1].Name != "a" || *rows[1].Volume != 2 || rows[1].Price != 3 {
+		t.Fatalf("unexpected row %v", rows[1])
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsOnParseError(t *testing.T) {
+	type row struct {
+		Date   time.Time `format:"2006-01-02"`
+		Volume int
+	}
+
+	var failed []string
+
+	options := Options{
+		OnParseError: func(column Column, raw string, err error) error {
+			if column.Header != "Volume" {
+				return err
+			}
+
+			failed = append(failed, raw)
+			return nil
+		},
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("date,volume\n2021-01-02,x\n2021-01-03,5\n"), true, &rows, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Volume != 0 || rows[1].Volume != 5 || len(failed) != 1 || failed[0] != "x" {
+		t.Fatalf("unexpected rows %v and failed %v", rows, failed)
+	}
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader("date,volume\n01/02/2021,1\n"), true, &rows, options)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Header != "Date" {
+		t.Fatalf("expected parse error for date but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsOnParseErrorRaw(t *testing.T) {
+	type row struct {
+		Code   int `trim:"*"`
+		Volume int `default:"x"`
+	}
+
+	var failed []string
+
+	options := Options{
+		OnParseError: func(column Column, raw string, err error) error {
+			failed = append(failed, raw)
+			return nil
+		},
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("code,volume\n*y*,\n"), true, &rows, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(failed, []string{"*y*", ""}) {
+		t.Fatalf("actual %q must be the raw cells", failed)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsDisallowExtraColumns(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var rows []row
+
+	input := "name,volume\na,1\nb,2,x\n"
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{FieldsPerRecord: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows = nil
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{FieldsPerRecord: -1, DisallowExtraColumns: true})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Row != 2 || parseErr.Column != 2 || parseErr.Value != "x" {
+		t.Fatalf("expected parse error for extra field but got %v", err)
+	}
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader("name,volume\na\n"), true, &rows, Options{FieldsPerRecord: -1, DisallowExtraColumns: true})
+	if !errors.As(err, &parseErr) || parseErr.Column != 1 {
+		t.Fatalf("expected parse error for missing field but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsPadShortRecords(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int `default:"-1"`
+		Note   string
+	}
+
+	var rows []row
+
+	input := "name,volume,note\na,1,x\nb\nc,3\n"
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{})
+	if err == nil {
+		t.Fatal("expected error for short record")
+	}
+
+	rows = nil
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{PadShortRecords: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []row{{"a", 1, "x"}, {"b", -1, ""}, {"c", 3, ""}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("actual %v must be %v", rows, expected)
+	}
+
+	var requiredRows []struct {
+		Name string
+		Note string `required:"true"`
+	}
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader("name,note\na,x\nb\n"), true, &requiredRows, Options{PadShortRecords: true})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Row != 2 || parseErr.Header != "Note" {
+		t.Fatalf("expected required error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsDisallowUnknownColumns(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader("volume,name\n1,a\n"), true, &rows, Options{DisallowUnknownColumns: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader("name,price,volume,date\na,1,2,3\n"), true, &rows, Options{DisallowUnknownColumns: true})
+	if err == nil || err.Error() != "unknown columns in header: price, date" {
+		t.Fatalf("expected unknown columns error but got %v", err)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsSkipRepeatedHeaders(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var rows []row
+
+	input := "name,volume\na,1\nname,volume\nb,2\n\xEF\xBB\xBFname,volume\nc,3\n"
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{})
+	if err == nil {
+		t.Fatal("expected error for repeated header")
+	}
+
+	rows = nil
+
+	err = ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{SkipRepeatedHeaders: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []row{{"a", 1}, {"b", 2}, {"c", 3}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("actual %v must be %v", rows, expected)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsHeaderRow(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var rows []row
+
+	input := "Daily Report\nGenerated 2021-01-02,by,admin\nname,volume\ntotal\na,1\n"
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(input), true, &rows, Options{HeaderRow: 2, Skip: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 || rows[0].Name != "a" || rows[0].Volume != 1 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadRowsFromReaderWithOptionsSizeHint(t *testing.T) {
+	prices := []dailyPrice{{Close: 1}}
+
+	err := ReadRowsFromReaderWithOptions(strings.NewReader(testData(t)), true, &prices, Options{SizeHint: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(prices); n != 11 {
+		t.Fatalf("prices must have 11 elements but has %d", n)
+	}
+
+	if c := cap(prices); c < 101 {
+		t.Fatalf("prices must have a capacity of at least 101 but has %d", c)
+	}
+
+	if prices[0].Close != 1 || prices[1].Close != 43.48 {
+		t.Fatalf("unexpected prices %v %v", prices[0], prices[1])
+	}
+}
+
+func TestReadRowsFromReaderWith(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var rows []row
+
+	input := "name; volume\n# note\njunk\n a ; 1\nb;2\nc;3\n"
+
+	err := ReadRowsFromReaderWith(strings.NewReader(input), true, &rows,
+		WithComma(';'), WithComment('#'), WithTrimSpace(true), WithSkip(1), WithLimit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Name != "a" || rows[0].Volume != 1 || rows[1].Name != "b" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestReadTableFromReaderWithOptionsVerifyTableLengths(t *testing.T) {
+	var prices stockPrices
+
+	err := ReadTableFromReaderWithOptions(strings.NewReader(testData(t)), true, &prices, Options{VerifyTableLengths: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prices = stockPrices{Close: []float64{1}}
+
+	err = ReadTableFromReaderWithOptions(strings.NewReader(testData(t)), true, &prices, Options{VerifyTableLengths: true})
+	if err == nil {
+		t.Fatal("expected error for different lengths")
+	}
+}
@@ -0,0 +1,161 @@
+package csv2
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// chunk is a byte range of whole data records.
+type chunk struct {
+	start int64
+	end   int64
+	lines int
+}
+
+// splitRecords scans the data for the record boundaries, taking the quoted
+// fields with newlines into account, and splits the data records into chunks
+// of about the given size. It returns the end of the header and the chunks.
+func splitRecords(reader io.ReaderAt, size int64, hasHeader bool, chunkSize int64) (int64, []chunk, error) {
+	bufferedReader := bufio.NewReader(io.NewSectionReader(reader, 0, size))
+
+	var headerEnd int64
+	var chunks []chunk
+
+	chunkStart := int64(0)
+	chunkLines := 0
+	lines := 0
+	inQuotes := false
+	readHeader := !hasHeader
+
+	for pos := int64(0); pos < size; pos++ {
+		b, err := bufferedReader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if b == '"' {
+			inQuotes = !inQuotes
+		}
+
+		if b == '\n' {
+			lines++
+		}
+
+		if b != '\n' || inQuotes {
+			continue
+		}
+
+		if !readHeader {
+			headerEnd = pos + 1
+			chunkStart = headerEnd
+			chunkLines = lines
+			readHeader = true
+		} else if pos+1-chunkStart >= chunkSize {
+			chunks = append(chunks, chunk{start: chunkStart, end: pos + 1, lines: chunkLines})
+			chunkStart = pos + 1
+			chunkLines = lines
+		}
+	}
+
+	if !readHeader {
+		headerEnd = size
+		chunkStart = size
+	}
+
+	if chunkStart < size {
+		chunks = append(chunks, chunk{start: chunkStart, end: size, lines: chunkLines})
+	}
+
+	return headerEnd, chunks, nil
+}
+
+// Read rows from reader at, parsing the chunks of the data concurrently with
+// the given number of workers, or one per CPU if it is not positive. The rows
+// are appended in their order in the data.
+func ReadRowsFromReaderAt(reader io.ReaderAt, size int64, hasHeader bool, rows interface{}, workers int) error {
+	rowType, err := getRowType(rows)
+	if err != nil {
+		return err
+	}
+
+	columns, err := getStructFieldsAsColumns(rowType, unmarshalerType)
+	if err != nil {
+		return err
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	headerEnd, chunks, err := splitRecords(reader, size, hasHeader, size/int64(workers)+1)
+	if err != nil {
+		return err
+	}
+
+	options := Options{}
+
+	headerReader, err := newRecordReader(context.Background(), io.NewSectionReader(reader, 0, headerEnd), hasHeader, columns, &options)
+	if err != nil {
+		return err
+	}
+
+	rowsSlice := reflect.ValueOf(rows).Elem()
+
+	chunkRows := make([]reflect.Value, len(chunks))
+	chunkErrs := make([]error, len(chunks))
+
+	var wg sync.WaitGroup
+
+	for i, c := range chunks {
+		wg.Add(1)
+
+		go func(i int, c chunk) {
+			defer wg.Done()
+
+			chunkOptions := Options{}
+
+			recordReader, err := newRecordReader(context.Background(), io.NewSectionReader(reader, c.start, c.end-c.start), false, nil, &chunkOptions)
+			if err != nil {
+				chunkErrs[i] = err
+				return
+			}
+
+			// The columns are already mapped to the header, so they are
+			// shared as is instead of being checked again.
+			recordReader.columns = headerReader.columns
+			recordReader.fieldCount = headerReader.fieldCount
+
+			chunkRows[i] = reflect.New(rowsSlice.Type()).Elem()
+			_, chunkErrs[i] = recordReader.readRows(chunkRows[i])
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	n := 0
+	length := rowsSlice.Len()
+
+	for i := range chunks {
+		if chunkErrs[i] != nil {
+			truncateRows(rowsSlice, length)
+
+			var parseErr *ParseError
+			if errors.As(chunkErrs[i], &parseErr) {
+				parseErr.Row += n
+				parseErr.Line += chunks[i].lines
+			}
+
+			return chunkErrs[i]
+		}
+
+		rowsSlice.Set(reflect.AppendSlice(rowsSlice, chunkRows[i]))
+		n += chunkRows[i].Len()
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package csv2
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadRowsFromReaderAt(t *testing.T) {
+	file, err := os.Open(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected []dailyPrice
+
+	err = ReadRowsFromString(testData(t), true, &expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{0, 1, 3, 100} {
+		var prices []dailyPrice
+
+		err = ReadRowsFromReaderAt(file, info.Size(), true, &prices, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(prices, expected) {
+			t.Fatalf("prices with %d workers must match the sequential read", workers)
+		}
+	}
+}
+
+func TestReadRowsFromReaderAtQuotedNewlines(t *testing.T) {
+	type row struct {
+		ID   int
+		Note string
+	}
+
+	var builder strings.Builder
+	builder.WriteString("id,note\n")
+
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&builder, "%d,\"line one\nline \"\"%d\"\"\"\n", i, i)
+	}
+
+	data := builder.String()
+
+	var rows []row
+
+	err := ReadRowsFromReaderAt(strings.NewReader(data), int64(len(data)), true, &rows, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 100 {
+		t.Fatalf("rows must have 100 elements but has %d", len(rows))
+	}
+
+	for i, r := range rows {
+		if expected := fmt.Sprintf("line one\nline \"%d\"", i); r.ID != i || r.Note != expected {
+			t.Fatalf("actual %v must be %d %q", r, i, expected)
+		}
+	}
+}
+
+func TestReadRowsFromReaderAtError(t *testing.T) {
+	type row struct {
+		ID int
+	}
+
+	var builder strings.Builder
+	builder.WriteString("id\n")
+
+	for i := 1; i <= 50; i++ {
+		if i == 42 {
+			builder.WriteString("x\n")
+		} else {
+			fmt.Fprintf(&builder, "%d\n", i)
+		}
+	}
+
+	data := builder.String()
+
+	var rows []row
+
+	err := ReadRowsFromReaderAt(strings.NewReader(data), int64(len(data)), true, &rows, 4)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Row != 42 || parseErr.Line != 43 {
+		t.Fatalf("expected parse error on row 42 but got %v", err)
+	}
+
+	if len(rows) != 0 {
+		t.Fatalf("rows must be empty but has %d elements", len(rows))
+	}
+}
@@ -0,0 +1,89 @@
+package csv2
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// errInvalidDelim is the error of encoding/csv for an invalid delimiter.
+var errInvalidDelim = errors.New("csv: invalid field or comment delimiter")
+
+// validDelim reports whether the rune is a valid delimiter like in
+// encoding/csv.
+func validDelim(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
+
+// recordWriter writes CSV records.
+type recordWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// quoteWriter is a record writer that quotes every field.
+type quoteWriter struct {
+	writer  *bufio.Writer
+	comma   rune
+	useCRLF bool
+	err     error
+}
+
+func newQuoteWriter(writer io.Writer, comma rune, useCRLF bool) *quoteWriter {
+	return &quoteWriter{
+		writer:  bufio.NewWriter(writer),
+		comma:   comma,
+		useCRLF: useCRLF,
+	}
+}
+
+// Write writes a single record with every field quoted.
+func (w *quoteWriter) Write(record []string) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if !validDelim(w.comma) {
+		return errInvalidDelim
+	}
+
+	for i, field := range record {
+		if i > 0 {
+			if _, w.err = w.writer.WriteRune(w.comma); w.err != nil {
+				return w.err
+			}
+		}
+
+		// The line breaks in the fields are written like csv.Writer does.
+		if w.useCRLF {
+			field = strings.ReplaceAll(strings.ReplaceAll(field, "\r", ""), "\n", "\r\n")
+		}
+
+		if _, w.err = w.writer.WriteString(`"` + strings.ReplaceAll(field, `"`, `""`) + `"`); w.err != nil {
+			return w.err
+		}
+	}
+
+	if w.useCRLF {
+		_, w.err = w.writer.WriteString("\r\n")
+	} else {
+		_, w.err = w.writer.WriteString("\n")
+	}
+
+	return w.err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *quoteWriter) Flush() {
+	if w.err == nil {
+		w.err = w.writer.Flush()
+	}
+}
+
+// Error reports any error that has occurred during a previous Write or Flush.
+func (w *quoteWriter) Error() error {
+	return w.err
+}
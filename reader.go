@@ -0,0 +1,394 @@
+package csv2
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// recordReader reads the data records of a CSV stream.
+type recordReader struct {
+	ctx        context.Context
+	csvReader  *csv.Reader
+	hasHeader  bool
+	headers    []string
+	columns    []Column
+	options    *Options
+	rowNumber  int
+	rowCount   int
+	fieldCount int
+	skipped    bool
+}
+
+func newRecordReader(ctx context.Context, reader io.Reader, hasHeader bool, columns []Column, options *Options) (*recordReader, error) {
+	r := &recordReader{
+		ctx:       ctx,
+		csvReader: newCsvReader(reader, *options),
+		hasHeader: hasHeader,
+		options:   options,
+	}
+
+	if hasHeader {
+		var err error
+
+		r.headers, err = readHeader(r.csvReader, options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.setColumns(columns); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// setColumns selects the columns to read and maps them to the header.
+func (r *recordReader) setColumns(columns []Column) error {
+	columns = r.options.selectColumns(columns)
+
+	if r.hasHeader {
+		if err := r.options.checkSelectedColumns(r.headers); err != nil {
+			return err
+		}
+
+		if err := mapHeader(r.headers, columns, r.options); err != nil {
+			return err
+		}
+
+		if r.options.DisallowUnknownColumns {
+			if unknown := unknownHeaders(r.headers, columns); len(unknown) > 0 {
+				return fmt.Errorf("unknown columns in header: %s", strings.Join(unknown, ", "))
+			}
+		}
+	} else if err := checkColumnIndices(columns); err != nil {
+		return err
+	}
+
+	r.columns = columns
+	r.fieldCount = 0
+
+	for _, column := range columns {
+		if column.ColumnIndex >= r.fieldCount {
+			r.fieldCount = column.ColumnIndex + 1
+		}
+	}
+
+	return nil
+}
+
+// checkExtraFields checks that the record has no fields beyond the mapped
+// columns if the options disallow them.
+func (r *recordReader) checkExtraFields(record []string) error {
+	if !r.options.DisallowExtraColumns || len(record) <= r.fieldCount {
+		return nil
+	}
+
+	return r.newParseError(record, Column{ColumnIndex: r.fieldCount}, record[r.fieldCount],
+		fmt.Errorf("record has %d fields but only %d expected", len(record), r.fieldCount))
+}
+
+// read reads the next data record and passes it to the set function. Records
+// failing to parse are skipped if the options allow it. It returns io.EOF
+// once there are no more records or the limit is reached, or the context
+// error once it is done.
+func (r *recordReader) read(set func(record []string) error) error {
+	if r.options.Limit > 0 && r.rowCount >= r.options.Limit {
+		return io.EOF
+	}
+
+	if !r.skipped {
+		if err := r.skip(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+
+		record, err := r.csvReader.Read()
+
+		isFieldCountErr := errors.Is(err, csv.ErrFieldCount)
+		if err != nil && !isFieldCountErr {
+			return err
+		}
+
+		r.rowNumber++
+
+		if isFieldCountErr {
+			err = r.fieldCountError(record)
+		} else if r.options.SkipRepeatedHeaders && r.isHeader(record) {
+			continue
+		} else if err = r.checkExtraFields(record); err == nil {
+			err = set(record)
+		}
+
+		if err == nil {
+			r.rowCount++
+			return nil
+		}
+
+		if !r.options.skipError(err) {
+			return err
+		}
+	}
+}
+
+// fieldCountError returns a parse error for the record that doesn't have the
+// expected number of fields, at its first missing or extra field.
+func (r *recordReader) fieldCountError(record []string) error {
+	expected := r.csvReader.FieldsPerRecord
+	column := Column{ColumnIndex: min(len(record), expected)}
+
+	if column.ColumnIndex < len(r.headers) {
+		column.Header = r.headers[column.ColumnIndex]
+	}
+
+	value := ""
+	if column.ColumnIndex < len(record) {
+		value = record[column.ColumnIndex]
+	}
+
+	return r.newParseError(record, column, value,
+		fmt.Errorf("record has %d fields but %d expected: %w", len(record), expected, csv.ErrFieldCount))
+}
+
+// isHeader reports whether the record repeats the header, as in the
+// concatenated files. The byte order mark of the repeated header is ignored.
+func (r *recordReader) isHeader(record []string) bool {
+	if !r.hasHeader || len(record) != len(r.headers) {
+		return false
+	}
+
+	for i, field := range record {
+		if i == 0 {
+			field = strings.TrimPrefix(field, string(bom))
+		}
+
+		if r.options.TrimSpace {
+			field = strings.TrimSpace(field)
+		}
+
+		if field != r.headers[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// skip discards the leading data records given by the options.
+func (r *recordReader) skip() error {
+	if err := discardRecords(r.csvReader, r.options.Skip); err != nil {
+		return err
+	}
+
+	r.rowNumber += r.options.Skip
+	r.skipped = true
+
+	return nil
+}
+
+// newParseError returns a parse error for the given column of the record
+// with the position of its field in the input.
+func (r *recordReader) newParseError(record []string, column Column, value string, err error) *ParseError {
+	parseErr := newParseError(r.rowNumber, column, value, err)
+
+	if len(record) > 0 {
+		parseErr.Line, parseErr.LineColumn = r.csvReader.FieldPos(min(column.ColumnIndex, len(record)-1))
+	}
+
+	return parseErr
+}
+
+// setColumnValue sets the value from the cell of the given column in the
+// record.
+func (r *recordReader) setColumnValue(value reflect.Value, record []string, column Column) error {
+	options := r.options
+
+	if column.ColumnIndex < 0 {
+		return nil
+	}
+
+	raw := ""
+	if column.ColumnIndex < len(record) {
+		raw = record[column.ColumnIndex]
+	} else if !options.PadShortRecords {
+		return r.newParseError(record, column, "",
+			fmt.Errorf("record has %d fields but column index %d requested", len(record), column.ColumnIndex))
+	}
+
+	stringValue := raw
+	if options.TrimSpace {
+		stringValue = strings.TrimSpace(stringValue)
+	}
+
+	if column.Trim != "" {
+		stringValue = strings.Trim(stringValue, column.Trim)
+	}
+
+	if options.isNullValue(stringValue) {
+		stringValue = ""
+	}
+
+	if stringValue == "" {
+		stringValue = column.Default
+	}
+
+	stringValue = transformValue(stringValue, column.Transform)
+
+	var err error
+	if column.Required && stringValue == "" {
+		err = errors.New("value is required")
+	} else if stringValue != "" {
+		err = checkEnumValue(stringValue, column.Enum)
+	}
+
+	if err == nil {
+		err = setValue(value, stringValue, column.Format, options)
+	}
+
+	if errors.Is(err, strconv.ErrRange) {
+		err = &rangeError{field: column.Name, fieldType: indirectType(value.Type()), value: stringValue, err: err}
+	}
+
+	if err != nil {
+		return options.parseError(value, column, raw, r.newParseError(record, column, stringValue, err))
+	}
+
+	return nil
+}
+
+// readRow reads the next data record into the fields of the given row.
+func (r *recordReader) readRow(row reflect.Value) error {
+	return r.read(func(record []string) error {
+		row.Set(reflect.Zero(row.Type()))
+
+		for _, column := range r.columns {
+			if err := r.setColumnValue(row.FieldByIndex(column.FieldIndex), record, column); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// readRows reads the remaining data records, appends them to the rows slice,
+// and returns the number of rows read. The slice elements can be structs or
+// pointers to structs. The rows are read in place into the grown slice.
+func (r *recordReader) readRows(rowsSlice reflect.Value) (int, error) {
+	rowType := rowsSlice.Type().Elem()
+
+	isPtr := rowType.Kind() == reflect.Ptr
+	if isPtr {
+		rowType = rowType.Elem()
+	}
+
+	if r.options.SizeHint > 0 {
+		rowsSlice.Grow(r.options.SizeHint)
+	}
+
+	n := 0
+
+	for {
+		i := rowsSlice.Len()
+
+		rowsSlice.Grow(1)
+		rowsSlice.SetLen(i + 1)
+
+		row := rowsSlice.Index(i)
+		if isPtr {
+			row.Set(reflect.New(rowType))
+			row = row.Elem()
+		}
+
+		err := r.readRow(row)
+		if err != nil {
+			rowsSlice.Index(i).SetZero()
+			rowsSlice.SetLen(i)
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	return n, nil
+}
+
+// readTable reads the remaining data records, appends them to the column
+// slices of the table, and returns the number of rows read.
+func (r *recordReader) readTable(tableValue reflect.Value) (int, error) {
+	itemValues := make([]reflect.Value, len(r.columns))
+
+	n := 0
+
+	for {
+		err := r.read(func(record []string) error {
+			for i, column := range r.columns {
+				itemValues[i] = reflect.New(tableValue.FieldByIndex(column.FieldIndex).Type().Elem()).Elem()
+
+				if err := r.setColumnValue(itemValues[i], record, column); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return n, err
+		}
+
+		for i, column := range r.columns {
+			sliceValue := tableValue.FieldByIndex(column.FieldIndex)
+			sliceValue.Set(reflect.Append(sliceValue, itemValues[i]))
+		}
+
+		n++
+	}
+
+	if r.options.VerifyTableLengths {
+		if err := r.verifyTableLengths(tableValue); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// verifyTableLengths checks that the column slices of the table have the same
+// length.
+func (r *recordReader) verifyTableLengths(tableValue reflect.Value) error {
+	if len(r.columns) == 0 {
+		return nil
+	}
+
+	expected := tableValue.FieldByIndex(r.columns[0].FieldIndex).Len()
+
+	for _, column := range r.columns[1:] {
+		if n := tableValue.FieldByIndex(column.FieldIndex).Len(); n != expected {
+			return fmt.Errorf("table field %s has %d elements but expected %d", column.Header, n, expected)
+		}
+	}
+
+	return nil
+}
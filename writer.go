@@ -0,0 +1,591 @@
+package csv2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshaler is the interface implemented by types that can marshal themselves into a CSV cell.
+type Marshaler interface {
+	MarshalCSV() (string, error)
+}
+
+var marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+
+func getFormatBase(format string) (int, error) {
+	base, err := parseBase(format)
+	if err != nil || base < 0 || base == 1 || base > 36 {
+		return 0, fmt.Errorf("invalid base format %q", format)
+	}
+
+	if base == 0 {
+		base = 10
+	}
+
+	return base, nil
+}
+
+// shiftDecimalPoint moves the decimal point of the given decimal number n
+// digits to the right.
+func shiftDecimalPoint(stringValue string, n int) string {
+	sign := ""
+	if strings.HasPrefix(stringValue, "-") {
+		sign, stringValue = "-", stringValue[1:]
+	}
+
+	integerPart, fractionPart, _ := strings.Cut(stringValue, ".")
+	fractionPart += strings.Repeat("0", max(n-len(fractionPart), 0))
+
+	integerPart = strings.TrimLeft(integerPart+fractionPart[:n], "0")
+	if integerPart == "" {
+		integerPart = "0"
+	}
+
+	if fractionPart = fractionPart[n:]; fractionPart != "" {
+		return sign + integerPart + "." + fractionPart
+	}
+
+	return sign + integerPart
+}
+
+func formatFloat(floatValue float64, bitSize int, format string, options *Options) string {
+	stringValue := strconv.FormatFloat(floatValue, 'f', -1, bitSize)
+
+	switch format {
+	case FormatPercent:
+		if math.IsInf(floatValue, 0) || math.IsNaN(floatValue) {
+			return stringValue
+		}
+
+		return options.localizeFloat(shiftDecimalPoint(stringValue, 2)) + "%"
+
+	case FormatPercentLiteral:
+		return options.localizeFloat(stringValue) + "%"
+
+	default:
+		return options.localizeFloat(stringValue)
+	}
+}
+
+func formatTime(timeValue time.Time, format string, options *Options) string {
+	layout, _, _ := strings.Cut(options.timeLayout(format), listSeparator)
+
+	switch layout {
+	case FormatUnix:
+		return strconv.FormatInt(timeValue.Unix(), 10)
+
+	case FormatUnixMilli:
+		return strconv.FormatInt(timeValue.UnixMilli(), 10)
+
+	case FormatExcel:
+		// The serial dates are the wall clock in the location like in reading.
+		if options.Location != nil {
+			wallTime := timeValue.In(options.Location)
+			timeValue = time.Date(wallTime.Year(), wallTime.Month(), wallTime.Day(),
+				wallTime.Hour(), wallTime.Minute(), wallTime.Second(), wallTime.Nanosecond(), time.UTC)
+		}
+
+		seconds := float64(timeValue.Unix()-excelEpoch.Unix()) + float64(timeValue.Nanosecond())/float64(time.Second)
+		return strconv.FormatFloat(seconds/(24*60*60), 'f', -1, 64)
+
+	default:
+		return timeValue.Format(layout)
+	}
+}
+
+func getValue(value reflect.Value, format string, options *Options) (string, error) {
+	if value.Kind() == reflect.Ptr && value.IsNil() {
+		return "", nil
+	}
+
+	if format == FormatJSON {
+		data, err := json.Marshal(value.Interface())
+		return string(data), err
+	}
+
+	if value.Type().Implements(marshalerType) {
+		return value.Interface().(Marshaler).MarshalCSV()
+	}
+
+	if value.CanAddr() && value.Addr().Type().Implements(marshalerType) {
+		return value.Addr().Interface().(Marshaler).MarshalCSV()
+	}
+
+	if value.Type() == durationType {
+		return time.Duration(value.Int()).String(), nil
+	}
+
+	if value.Type() == rawMessageType {
+		return string(value.Bytes()), nil
+	}
+
+	if value.Type() == ipType {
+		if value.Len() == 0 {
+			return "", nil
+		}
+
+		return value.Interface().(net.IP).String(), nil
+	}
+
+	kind := value.Kind()
+
+	switch kind {
+	case reflect.String:
+		return value.String(), nil
+
+	case reflect.Bool:
+		if format == FormatNonEmpty && !value.Bool() {
+			return "", nil
+		}
+
+		return strconv.FormatBool(value.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		base, err := getFormatBase(format)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.FormatInt(value.Int(), base), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		base, err := getFormatBase(format)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.FormatUint(value.Uint(), base), nil
+
+	case reflect.Float32:
+		return formatFloat(value.Float(), 32, format, options), nil
+
+	case reflect.Float64:
+		return formatFloat(value.Float(), 64, format, options), nil
+
+	case reflect.Complex64:
+		return strconv.FormatComplex(value.Complex(), 'f', -1, 64), nil
+
+	case reflect.Complex128:
+		return strconv.FormatComplex(value.Complex(), 'f', -1, 128), nil
+
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.Uint8 {
+			return "", fmt.Errorf("unsupported slice type %s", value.Type())
+		}
+
+		if format == FormatBase64 {
+			return base64.StdEncoding.EncodeToString(value.Bytes()), nil
+		}
+
+		return string(value.Bytes()), nil
+
+	case reflect.Ptr:
+		return getValue(value.Elem(), format, options)
+
+	case reflect.Struct:
+		typeString := value.Type().String()
+
+		switch typeString {
+		case "time.Time":
+			return formatTime(value.Interface().(time.Time), format, options), nil
+
+		case "url.URL":
+			actualValue := value.Interface().(url.URL)
+			return actualValue.String(), nil
+
+		case "big.Int":
+			actualValue := value.Interface().(big.Int)
+			return actualValue.String(), nil
+
+		case "big.Float":
+			actualValue := value.Interface().(big.Float)
+			return options.localizeFloat(actualValue.Text('f', -1)), nil
+
+		default:
+			if isNullType(value.Type()) {
+				if !value.Field(1).Bool() {
+					return "", nil
+				}
+
+				return getValue(value.Field(0), format, options)
+			}
+
+			baseType := baseStructType(value.Type())
+			if baseType == nil {
+				return "", fmt.Errorf("unsupported struct type %s", typeString)
+			}
+
+			return getValue(value.Convert(baseType), format, options)
+		}
+
+	default:
+		return "", fmt.Errorf("unsupported value kind %s", kind)
+	}
+}
+
+// computedColumn is a column whose values are computed from the rows when
+// writing instead of being read from a struct field.
+type computedColumn struct {
+	header  string
+	compute func(row interface{}) (string, error)
+}
+
+func writeHeaderRecord(csvWriter recordWriter, columns []Column, computed []computedColumn) error {
+	headers := make([]string, recordLength(columns), recordLength(columns)+len(computed))
+	for _, column := range columns {
+		if column.WriteHeader != "" {
+			headers[column.ColumnIndex] = column.WriteHeader
+		} else {
+			headers[column.ColumnIndex] = column.Header
+
+			// The first occurrence is the default, so "#1" is kept as a
+			// part of the header.
+			if name, occurrence, explicit := parseHeaderOccurrence(column.Header); explicit && occurrence > 1 {
+				headers[column.ColumnIndex] = name
+			}
+		}
+	}
+
+	for _, column := range computed {
+		headers = append(headers, column.header)
+	}
+
+	return csvWriter.Write(headers)
+}
+
+// writeRow writes the fields of the given row at their column indices
+// followed by the computed columns, using the record as the buffer.
+func writeRow(csvWriter recordWriter, row reflect.Value, columns []Column, computed []computedColumn, record []string, options *Options) error {
+	for _, column := range columns {
+		stringValue, err := getValue(row.FieldByIndex(column.FieldIndex), column.Format, options)
+		if err != nil {
+			return err
+		}
+
+		record[column.ColumnIndex] = stringValue
+	}
+
+	n := recordLength(columns)
+
+	for i, column := range computed {
+		stringValue, err := column.compute(row.Interface())
+		if err != nil {
+			return fmt.Errorf("computed column %s: %w", column.header, err)
+		}
+
+		record[n+i] = stringValue
+	}
+
+	return csvWriter.Write(record)
+}
+
+// Write rows to writer.
+func WriteRowsToWriter(writer io.Writer, writeHeader bool, rows interface{}) error {
+	return WriteRowsToWriterWithOptions(writer, writeHeader, rows, Options{})
+}
+
+// Write rows to writer with options.
+func WriteRowsToWriterWithOptions(writer io.Writer, writeHeader bool, rows interface{}, options Options) error {
+	rowsSlice := reflect.ValueOf(rows)
+	if rowsSlice.Kind() == reflect.Ptr {
+		rowsSlice = rowsSlice.Elem()
+	}
+
+	if rowsSlice.Kind() != reflect.Slice {
+		return fmt.Errorf("rows %w", ErrNotSlice)
+	}
+
+	rowType := rowsSlice.Type().Elem()
+	if rowType.Kind() == reflect.Ptr {
+		rowType = rowType.Elem()
+	}
+
+	if rowType.Kind() != reflect.Struct {
+		return fmt.Errorf("rows element %w", ErrNotStruct)
+	}
+
+	columns, err := getStructFieldsAsColumns(rowType, marshalerType)
+	if err != nil {
+		return err
+	}
+
+	columns, err = options.orderColumns(columns)
+	if err != nil {
+		return err
+	}
+
+	if err := checkColumnIndices(columns); err != nil {
+		return err
+	}
+
+	csvWriter := newCsvWriter(writer, options)
+
+	if writeHeader {
+		if err := writeHeaderRecord(csvWriter, columns, nil); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, recordLength(columns))
+
+	for i := 0; i < rowsSlice.Len(); i++ {
+		row := rowsSlice.Index(i)
+		if row.Kind() == reflect.Ptr {
+			if row.IsNil() {
+				return fmt.Errorf("row %d is nil", i)
+			}
+
+			row = row.Elem()
+		}
+
+		if err := writeRow(csvWriter, row, columns, nil, record, &options); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
+// closeFile closes the file and sets the error to the close error if it is
+// nil, so that the failed writes on close are reported.
+func closeFile(file *os.File, err *error) {
+	if closeErr := file.Close(); *err == nil {
+		*err = closeErr
+	}
+}
+
+// Write rows to file.
+func WriteRowsToFile(fileName string, writeHeader bool, rows interface{}) (err error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	defer closeFile(file, &err)
+
+	return WriteRowsToWriter(file, writeHeader, rows)
+}
+
+// Append rows to file without a header. The file is created if it does not
+// exist, and the header is written first if the file is empty.
+func AppendRowsToFile(fileName string, rows interface{}) (err error) {
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer closeFile(file, &err)
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return WriteRowsToWriter(file, info.Size() == 0, rows)
+}
+
+// Write table to writer.
+func WriteTableToWriter(writer io.Writer, writeHeader bool, table interface{}) error {
+	return WriteTableToWriterWithOptions(writer, writeHeader, table, Options{})
+}
+
+// Write table to writer with options.
+func WriteTableToWriterWithOptions(writer io.Writer, writeHeader bool, table interface{}, options Options) error {
+	tableValue := reflect.ValueOf(table)
+	if tableValue.Kind() == reflect.Ptr {
+		tableValue = tableValue.Elem()
+	}
+
+	if tableValue.Kind() != reflect.Struct {
+		return fmt.Errorf("table %w", ErrNotStruct)
+	}
+
+	tableType := tableValue.Type()
+
+	columns, err := getTableFieldsAsColumns(tableType, marshalerType)
+	if err != nil {
+		return err
+	}
+
+	columns, err = options.orderColumns(columns)
+	if err != nil {
+		return err
+	}
+
+	if err := checkColumnIndices(columns); err != nil {
+		return err
+	}
+
+	// The nil slices are written as empty cells, and the other slices must
+	// have the same length.
+	numRows := 0
+
+	for _, column := range columns {
+		numRows = max(numRows, tableValue.FieldByIndex(column.FieldIndex).Len())
+	}
+
+	for _, column := range columns {
+		sliceValue := tableValue.FieldByIndex(column.FieldIndex)
+
+		if !sliceValue.IsNil() && sliceValue.Len() != numRows {
+			return fmt.Errorf("table field %s has %d elements but expected %d", column.Header, sliceValue.Len(), numRows)
+		}
+	}
+
+	csvWriter := newCsvWriter(writer, options)
+
+	if writeHeader {
+		if err := writeHeaderRecord(csvWriter, columns, nil); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, recordLength(columns))
+
+	for i := 0; i < numRows; i++ {
+		for _, column := range columns {
+			sliceValue := tableValue.FieldByIndex(column.FieldIndex)
+			if sliceValue.IsNil() {
+				record[column.ColumnIndex] = ""
+				continue
+			}
+
+			stringValue, err := getValue(sliceValue.Index(i), column.Format, &options)
+			if err != nil {
+				return err
+			}
+
+			record[column.ColumnIndex] = stringValue
+		}
+
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
+// Write table to file.
+func WriteTableToFile(fileName string, writeHeader bool, table interface{}) (err error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	defer closeFile(file, &err)
+
+	return WriteTableToWriter(file, writeHeader, table)
+}
+
+// Writer writes batches of rows to a CSV stream with the same options. The
+// header is written with the first batch using the tags of its struct type.
+type Writer struct {
+	// Options for writing. They must be set before the first write.
+	Options
+
+	// WriteHeader indicates that the header is written before the first
+	// batch. It defaults to true.
+	WriteHeader bool
+
+	writer   io.Writer
+	computed []computedColumn
+	encoder  *Encoder
+}
+
+// NewWriter returns a new writer that writes to writer.
+func NewWriter(writer io.Writer) *Writer {
+	return &Writer{
+		WriteHeader: true,
+		writer:      writer,
+	}
+}
+
+// WriteRows writes the given slice of rows, which are structs or pointers to
+// structs. All rows must be of the same type as the first batch.
+func (w *Writer) WriteRows(rows interface{}) error {
+	rowsSlice := reflect.ValueOf(rows)
+	if rowsSlice.Kind() == reflect.Ptr {
+		rowsSlice = rowsSlice.Elem()
+	}
+
+	if rowsSlice.Kind() != reflect.Slice {
+		return fmt.Errorf("rows %w", ErrNotSlice)
+	}
+
+	rowType := rowsSlice.Type().Elem()
+	if rowType.Kind() == reflect.Ptr {
+		rowType = rowType.Elem()
+	}
+
+	if rowType.Kind() != reflect.Struct {
+		return fmt.Errorf("rows element %w", ErrNotStruct)
+	}
+
+	if w.encoder == nil {
+		w.encoder = &Encoder{
+			Options:     w.Options,
+			WriteHeader: w.WriteHeader,
+			writer:      w.writer,
+			computed:    w.computed,
+		}
+	}
+
+	if err := w.encoder.setRowType(rowType); err != nil {
+		return err
+	}
+
+	for i := 0; i < rowsSlice.Len(); i++ {
+		row := rowsSlice.Index(i)
+		if row.Kind() == reflect.Ptr {
+			if row.IsNil() {
+				return fmt.Errorf("row %d is nil", i)
+			}
+
+			row = row.Elem()
+		}
+
+		if err := writeRow(w.encoder.csvWriter, row, w.encoder.columns, w.encoder.computed, w.encoder.record, &w.encoder.Options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddComputedColumn adds a column with the given header after the columns of
+// the struct fields. Its values are computed by calling compute with each row
+// struct. The computed columns must be added before the first write.
+func (w *Writer) AddComputedColumn(header string, compute func(row interface{}) (string, error)) {
+	w.computed = append(w.computed, computedColumn{header: header, compute: compute})
+}
+
+// Flush writes any buffered rows to the underlying writer.
+func (w *Writer) Flush() error {
+	if w.encoder == nil {
+		return nil
+	}
+
+	return w.encoder.Flush()
+}
+
+// Close flushes the writer. Like gzip.Writer, it does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	return w.Flush()
+}
@@ -0,0 +1,1021 @@
+package csv2
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestWriteRowsToWriter(t *testing.T) {
+	var prices []dailyPrice
+
+	err := ReadRowsFromFile(testFile, true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, true, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []dailyPrice
+
+	err = ReadRowsFromReader(&buffer, true, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(actual); n != len(prices) {
+		t.Fatalf("actual must have %d elements but has %d", len(prices), n)
+	}
+
+	for i := range prices {
+		if !actual[i].Date.Equal(prices[i].Date) {
+			t.Fatalf("row %d date %v must be %v", i, actual[i].Date, prices[i].Date)
+		}
+
+		actual[i].Date = prices[i].Date
+
+		if actual[i] != prices[i] {
+			t.Fatalf("row %d %v must be %v", i, actual[i], prices[i])
+		}
+	}
+}
+
+func TestWriteTableToWriter(t *testing.T) {
+	prices := stockPrices{}
+
+	err := ReadTableFromFile(testFile, true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteTableToWriter(&buffer, true, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := stockPrices{}
+
+	err = ReadTableFromReader(&buffer, true, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(actual.Date); n != len(prices.Date) {
+		t.Fatalf("date must have %d elements but has %d", len(prices.Date), n)
+	}
+
+	for i := range prices.Close {
+		if actual.Close[i] != prices.Close[i] {
+			t.Fatalf("close %d is %f but must be %f", i, actual.Close[i], prices.Close[i])
+		}
+	}
+}
+
+func TestWriteTableToWriterDifferentLengths(t *testing.T) {
+	table := struct {
+		Name []string
+		Age  []int
+	}{
+		Name: []string{"a", "b"},
+		Age:  []int{1},
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteTableToWriter(&buffer, true, table)
+	if err == nil {
+		t.Fatal("expected error for different lengths")
+	}
+}
+
+func TestWriteTableToWriterNilSlices(t *testing.T) {
+	table := struct {
+		Name []string
+		Age  []int
+		City []string
+	}{
+		Name: []string{"a", "b"},
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteTableToWriter(&buffer, true, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Name,Age,City\na,,\nb,,\n"
+	if buffer.String() != expected {
+		t.Fatalf("actual %q must be %q", buffer.String(), expected)
+	}
+
+	buffer.Reset()
+
+	err = WriteTableToWriter(&buffer, true, struct{ Name []string }{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buffer.String() != "Name\n" {
+		t.Fatalf("actual %q must be %q", buffer.String(), "Name\n")
+	}
+}
+
+func TestWriteRowsToWriterIndexTag(t *testing.T) {
+	type row struct {
+		Date  string
+		Close float64 `index:"2"`
+		Open  float64 `index:"1"`
+		Note  string  `index:"4"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Date: "d", Close: 2.5, Open: 1.5, Note: "n"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "d,1.5,2.5,,n\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+
+	var rows []row
+
+	err = ReadRowsFromReader(&buffer, false, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0] != (row{Date: "d", Close: 2.5, Open: 1.5, Note: "n"}) {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+
+	var table struct {
+		Date  []string
+		Close []float64 `index:"2"`
+		Open  []float64 `index:"1"`
+	}
+
+	table.Date, table.Close, table.Open = []string{"d"}, []float64{2.5}, []float64{1.5}
+
+	buffer.Reset()
+
+	err = WriteTableToWriter(&buffer, true, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = "Date,Open,Close\nd,1.5,2.5\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterDuplicateIndex(t *testing.T) {
+	type row struct {
+		A string `index:"1"`
+		B string
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{A: "a", B: "b"}})
+	if err == nil || err.Error() != "duplicate index 1 for fields A and B" {
+		t.Fatalf("expected duplicate index error but got %v", err)
+	}
+
+	var rows []row
+
+	err = ReadRowsFromReader(strings.NewReader("a,b\n"), false, &rows)
+	if err == nil {
+		t.Fatal("expected duplicate index error")
+	}
+}
+
+func TestWriteRowsToWriterSkipField(t *testing.T) {
+	type person struct {
+		Name     string `header:"name"`
+		Computed int    `header:"-"`
+		Age      int    `header:"age"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, true, []person{{Name: "alice", Computed: 1, Age: 42}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "name,age\nalice,42\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterPointerFields(t *testing.T) {
+	type row struct {
+		Name  string
+		Price *float64
+	}
+
+	price := 1.5
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Name: "a"}, {Name: "b", Price: &price}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "a,\nb,1.5\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterDuration(t *testing.T) {
+	type interval struct {
+		Name     string
+		Duration time.Duration
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []interval{{Name: "a", Duration: 90 * time.Minute}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "a,1h30m0s\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToFile(t *testing.T) {
+	var prices []dailyPrice
+
+	err := ReadRowsFromFile(testFile, true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "rows.csv")
+
+	err = WriteRowsToFile(fileName, true, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []dailyPrice
+
+	err = ReadRowsFromFile(fileName, true, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(actual); n != len(prices) {
+		t.Fatalf("actual must have %d elements but has %d", len(prices), n)
+	}
+}
+
+func TestWriteTableToFile(t *testing.T) {
+	prices := stockPrices{}
+
+	err := ReadTableFromFile(testFile, true, &prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "table.csv")
+
+	err = WriteTableToFile(fileName, true, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := stockPrices{}
+
+	err = ReadTableFromFile(fileName, true, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(actual.Date); n != len(prices.Date) {
+		t.Fatalf("date must have %d elements but has %d", len(prices.Date), n)
+	}
+}
+
+func TestWriteRowsToFileInvalidPath(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "missing", "rows.csv")
+
+	err := WriteRowsToFile(fileName, true, []dailyPrice{})
+	if err == nil {
+		t.Fatal("expected error for invalid path")
+	}
+}
+
+func TestWriteRowsToWriterEmbeddedStruct(t *testing.T) {
+	type user struct {
+		Name string `header:"name"`
+		auditable
+	}
+
+	users := []user{
+		{
+			Name: "alice",
+			auditable: auditable{
+				CreatedAt: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+				UpdatedAt: time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, true, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "name,created_at,updated_at\nalice,2021-01-02,2021-02-03\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterComplex(t *testing.T) {
+	type sample struct {
+		Small complex64
+		Large complex128
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []sample{{Small: 1 + 2i, Large: -1.5 + 0.25i}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "(1+2i),(-1.5+0.25i)\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+
+	var actual []sample
+
+	err = ReadRowsFromReader(strings.NewReader(buffer.String()), false, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual[0].Small != 1+2i || actual[0].Large != -1.5+0.25i {
+		t.Fatalf("unexpected sample %v", actual[0])
+	}
+}
+
+func TestWriteRowsToWriterIntegerBase(t *testing.T) {
+	type row struct {
+		Hex    int   `format:"base=16"`
+		Binary uint8 `format:"base=2"`
+		Auto   int64 `format:"base=0"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Hex: 31, Binary: 5, Auto: 15}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1f,101,15\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func (m money) MarshalCSV() (string, error) {
+	return fmt.Sprintf("$%d.%02d", m/100, m%100), nil
+}
+
+// Point with a pointer receiver marshaler.
+type point struct {
+	X, Y int
+}
+
+func (p *point) MarshalCSV() (string, error) {
+	return fmt.Sprintf("%d:%d", p.X, p.Y), nil
+}
+
+func TestWriteRowsToWriterMarshaler(t *testing.T) {
+	type row struct {
+		Amount   money
+		Optional *money
+		Location point
+		Pointer  *point
+	}
+
+	amount := money(100)
+
+	rows := []row{
+		{Amount: 123456, Optional: &amount, Location: point{1, 2}, Pointer: &point{3, 4}},
+		{Amount: 10},
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "$1234.56,$1.00,1:2,3:4\n$0.10,,0:0,\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterIPAndURL(t *testing.T) {
+	type access struct {
+		Address net.IP
+		Page    *url.URL
+	}
+
+	page, err := url.Parse("https://example.com/a?b=c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, false, []access{{Address: net.IPv4(10, 0, 0, 1), Page: page}, {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "10.0.0.1,https://example.com/a?b=c\n,\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterWithOptionsAlwaysQuote(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriterWithOptions(&buffer, true, []row{{Name: `a "b"`, Volume: 1}}, Options{AlwaysQuote: true, Comma: ';'})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "\"name\";\"volume\"\n\"a \"\"b\"\"\";\"1\"\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+
+	var actual []row
+
+	err = ReadRowsFromReaderWithOptions(&buffer, true, &actual, Options{Comma: ';'})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual[0].Name != `a "b"` || actual[0].Volume != 1 {
+		t.Fatalf("unexpected row %v", actual[0])
+	}
+}
+
+func TestWriteRowsToWriterWithOptionsUseCRLF(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	rows := []row{{Name: "a", Volume: 1}}
+
+	for _, alwaysQuote := range []bool{false, true} {
+		var buffer bytes.Buffer
+
+		err := WriteRowsToWriterWithOptions(&buffer, true, rows, Options{UseCRLF: true, AlwaysQuote: alwaysQuote})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "name,volume\r\na,1\r\n"
+		if alwaysQuote {
+			expected = "\"name\",\"volume\"\r\n\"a\",\"1\"\r\n"
+		}
+
+		if actual := buffer.String(); actual != expected {
+			t.Fatalf("actual %q must be %q", actual, expected)
+		}
+	}
+}
+
+func TestWriteRowsToWriterWithOptionsAlwaysQuoteLikeCSV(t *testing.T) {
+	type row struct {
+		Note string
+	}
+
+	rows := []row{{Note: "a\nb\r\nc\rd"}}
+
+	for _, useCRLF := range []bool{false, true} {
+		var expected, actual bytes.Buffer
+
+		csvWriter := csv.NewWriter(&expected)
+		csvWriter.UseCRLF = useCRLF
+		csvWriter.Write([]string{rows[0].Note})
+		csvWriter.Flush()
+
+		err := WriteRowsToWriterWithOptions(&actual, false, rows, Options{UseCRLF: useCRLF, AlwaysQuote: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual.String() != expected.String() {
+			t.Fatalf("actual %q must be %q", actual.String(), expected.String())
+		}
+	}
+
+	for _, alwaysQuote := range []bool{false, true} {
+		var buffer bytes.Buffer
+
+		err := WriteRowsToWriterWithOptions(&buffer, false, rows, Options{Comma: '"', AlwaysQuote: alwaysQuote})
+		if err == nil || err.Error() != "csv: invalid field or comment delimiter" {
+			t.Fatalf("expected invalid delimiter error but got %v", err)
+		}
+	}
+}
+
+func TestWriteRowsToWriterPointerRows(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []*row{{Name: "a", Volume: 1}, {Name: "b", Volume: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "a,1\nb,2\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+
+	err = WriteRowsToWriter(&buffer, false, []*row{nil})
+	if err == nil {
+		t.Fatal("expected error for nil row")
+	}
+}
+
+func TestWriteRowsToWriterNonEmptyBool(t *testing.T) {
+	type row struct {
+		Name    string
+		Checked bool `format:"nonempty"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Name: "a", Checked: true}, {Name: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "a,true\nb,\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterBigNumbers(t *testing.T) {
+	type row struct {
+		Amount *big.Int
+		Rate   *big.Float
+	}
+
+	amount, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Amount: amount, Rate: big.NewFloat(0.125)}, {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "123456789012345678901234567890,0.125\n,\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterJSON(t *testing.T) {
+	type row struct {
+		Raw    json.RawMessage
+		Labels []string `format:"json"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Raw: json.RawMessage(`{"x":1}`), Labels: []string{"a", "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "\"{\"\"x\"\":1}\",\"[\"\"a\"\",\"\"b\"\"]\"\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterTimeLayouts(t *testing.T) {
+	type row struct {
+		Date time.Time `format:"2006-01-02|01/02/2006"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Date: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "2021-01-02\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterWriteHeaderTag(t *testing.T) {
+	type row struct {
+		AdjClose float64 `header:"adj_close_raw" writeHeader:"Adjusted Close"`
+		Volume   int     `csv:"vol,writeHeader=Volume"`
+		Name     string
+	}
+
+	var rows []row
+
+	err := ReadRowsFromReader(strings.NewReader("name,vol,adj_close_raw\na,1,2.5\n"), true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+
+	err = WriteRowsToWriter(&buffer, true, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Adjusted Close,Volume,Name\n2.5,1,a\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterPercent(t *testing.T) {
+	type row struct {
+		Rate    float64 `format:"percent"`
+		Literal float64 `format:"percent-literal"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Rate: 0.125, Literal: 12.5}, {Rate: 0.07, Literal: -3}, {Rate: -12.3456}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "12.5%,12.5%\n7%,-3%\n-1234.56%,0%\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterBytes(t *testing.T) {
+	type row struct {
+		Token   []byte
+		Payload []byte `format:"base64"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Token: []byte("abc"), Payload: []byte("hello")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "abc,aGVsbG8=\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestAppendRowsToFile(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+	}
+
+	fileName := filepath.Join(t.TempDir(), "rows.csv")
+
+	for i := 1; i <= 2; i++ {
+		err := AppendRowsToFile(fileName, []row{{Name: "a", Volume: i}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actual, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Name,Volume\na,1\na,2\n"
+	if string(actual) != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterWithOptionsOrder(t *testing.T) {
+	type row struct {
+		Name   string
+		Volume int
+		Price  float64 `writeHeader:"Cost"`
+	}
+
+	var buffer bytes.Buffer
+
+	rows := []row{{Name: "a", Volume: 1, Price: 2.5}}
+
+	err := WriteRowsToWriterWithOptions(&buffer, true, rows, Options{Order: []string{"cost", "name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Cost,Name\n2.5,a\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+
+	err = WriteRowsToWriterWithOptions(&buffer, true, rows, Options{Order: []string{"name", "date"}})
+	if err == nil || err.Error() != "columns not found in struct: date" {
+		t.Fatalf("expected columns not found error but got %v", err)
+	}
+}
+
+func TestWriteRowsToWriterSQLNullTypes(t *testing.T) {
+	type row struct {
+		Name   sql.NullString
+		Volume sql.NullInt64
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Name: sql.NullString{String: "a", Valid: true}, Volume: sql.NullInt64{Int64: 5}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "a,\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+}
+
+func TestWriteRowsToWriterExcelTime(t *testing.T) {
+	type row struct {
+		Date time.Time `format:"excel"`
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, false, []row{{Date: time.Date(2021, 1, 1, 18, 0, 0, 0, time.UTC)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := buffer.String(); actual != "44197.75\n" {
+		t.Fatalf("actual %q must be %q", actual, "44197.75\n")
+	}
+}
+
+func TestWriteRowsToWriterExcelTimeLocation(t *testing.T) {
+	type row struct {
+		Date time.Time `format:"excel"`
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second serial is noon of a daylight saving time change day.
+	for _, location := range []*time.Location{time.FixedZone("EST", -5*60*60), newYork} {
+		options := Options{Location: location}
+
+		var rows []row
+
+		err := ReadRowsFromReaderWithOptions(strings.NewReader("44197.5\n44269.5\n"), false, &rows, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := time.Date(2021, 3, 14, 12, 0, 0, 0, location); !rows[1].Date.Equal(expected) {
+			t.Fatalf("date %v must be %v", rows[1].Date, expected)
+		}
+
+		var buffer bytes.Buffer
+
+		err = WriteRowsToWriterWithOptions(&buffer, false, rows, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := buffer.String(); actual != "44197.5\n44269.5\n" {
+			t.Fatalf("actual %q must be %q", actual, "44197.5\n44269.5\n")
+		}
+	}
+}
+
+func TestWriteRowsToWriterTimeFormats(t *testing.T) {
+	type row struct {
+		Unix      time.Time `format:"unix"`
+		UnixMilli time.Time `format:"unixmilli"`
+		RFC3339   time.Time `format:"2006-01-02T15:04:05Z07:00"`
+	}
+
+	date := time.Date(2021, 1, 2, 3, 4, 5, 6000000, time.UTC)
+
+	var buffer bytes.Buffer
+
+	err := WriteRowsToWriter(&buffer, true, []row{{Unix: date, UnixMilli: date, RFC3339: date}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Unix,UnixMilli,RFC3339\n1609556645,1609556645006,2021-01-02T03:04:05Z\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+
+	var rows []row
+
+	err = ReadRowsFromReader(&buffer, true, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !rows[0].Unix.Equal(date.Truncate(time.Second)) || !rows[0].UnixMilli.Equal(date) || !rows[0].RFC3339.Equal(date.Truncate(time.Second)) {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+}
+
+func TestWriterWriteRows(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	var buffer bytes.Buffer
+
+	writer := NewWriter(&buffer)
+	writer.Comma = ';'
+	writer.UseCRLF = true
+
+	if err := writer.WriteRows([]row{{Name: "a", Volume: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.WriteRows([]*row{{Name: "b", Volume: 2}, {Name: "c", Volume: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "name;volume\r\na;1\r\nb;2\r\nc;3\r\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+
+	if err := writer.WriteRows([]struct{ Other int }{{1}}); err == nil {
+		t.Fatal("expected error for different row type")
+	}
+
+	if err := writer.WriteRows([]*row{nil}); err == nil || err.Error() != "row 0 is nil" {
+		t.Fatalf("expected nil row error but got %v", err)
+	}
+}
+
+func TestWriterCloseLeavesFileOpen(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "rows.csv")
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := NewWriter(file)
+	writer.WriteHeader = false
+
+	if err := writer.WriteRows([]struct{ Name string }{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.WriteRows([]struct{ Name string }{{"a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "a\n" {
+		t.Fatalf("actual %q must be %q", string(data), "a\n")
+	}
+
+	if _, err := file.WriteString("b\n"); err != nil {
+		t.Fatalf("file must be left open but got %v", err)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriterAddComputedColumn(t *testing.T) {
+	type row struct {
+		Name   string `header:"name"`
+		Volume int    `header:"volume"`
+	}
+
+	var buffer bytes.Buffer
+
+	writer := NewWriter(&buffer)
+
+	sequence := 0
+	writer.AddComputedColumn("seq", func(row interface{}) (string, error) {
+		sequence++
+		return fmt.Sprint(sequence), nil
+	})
+
+	writer.AddComputedColumn("double", func(r interface{}) (string, error) {
+		return fmt.Sprint(r.(row).Volume * 2), nil
+	})
+
+	if err := writer.WriteRows([]*row{{Name: "a", Volume: 1}, {Name: "b", Volume: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "name,volume,seq,double\na,1,1,2\nb,2,2,4\n"
+	if actual := buffer.String(); actual != expected {
+		t.Fatalf("actual %q must be %q", actual, expected)
+	}
+
+	writer = NewWriter(&buffer)
+	writer.AddComputedColumn("fail", func(row interface{}) (string, error) {
+		return "", errors.New("failed")
+	})
+
+	if err := writer.WriteRows([]row{{Name: "a"}}); err == nil || err.Error() != "computed column fail: failed" {
+		t.Fatalf("expected computed column error but got %v", err)
+	}
+}